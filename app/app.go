@@ -1,15 +1,30 @@
 package app
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
 	"k8s.io/api/apps/v1"
 	"sigs.k8s.io/yaml"
 	"encoding/json"
 )
 
+const resourceType = "deployment"
+
 type deployment struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
 	err 					error
 }
 
@@ -31,11 +46,25 @@ type Deployment interface {
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
 	GetStatus(namespace string, deploymentName string) (deploymentStatus *DeploymentStatus, err error)
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
+	GetStatusContext(ctx context.Context, namespace string, deploymentName string) (deploymentStatus *DeploymentStatus, err error)
 }
 
-func NewDeploymentForClient(client *kubernetes.Clientset) *deployment {
+func NewDeploymentForClient(client kubernetes.Interface) *deployment {
 	return &deployment{
 		client: client,
 	}
@@ -45,40 +74,79 @@ func (c *deployment) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *deployment) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors and update conflicts, nil sends every request once
+func (c *deployment) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+// SetApplyOptions sets the field manager and force flag of Apply, nil uses the defaults
+func (c *deployment) SetApplyOptions(options *apply.Options) {
+	c.applyOptions = options
+}
+
+func (c *deployment) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 // trigger deployment include job change and rollback
 func (c *deployment) Trigger(namespace string, deploymentName string, imageName string, imageTag string) (err error) {
+	return c.TriggerContext(context.Background(), namespace, deploymentName, imageName, imageTag)
+}
+
+func (c *deployment) TriggerContext(ctx context.Context, namespace string, deploymentName string, imageName string, imageTag string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	// get deployment
-	deployment, err := c.client.AppsV1().Deployments(namespace).Get(deploymentName, metav1.GetOptions{})
-	if err != nil {
-		return
-	}
-	// update job
+	// get and update in one attempt, so a conflict re-reads the deployment and changes the image again
 	image := imageName + ":" + imageTag
-	deployment.Spec.Template.Spec.Containers[0].Image = image
-	_, err = c.client.AppsV1().Deployments(namespace).Update(deployment)
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		// get deployment
+		deployment, err := c.client.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// update job
+		deployment.Spec.Template.Spec.Containers[0].Image = image
+		_, err = c.client.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, deploymentName)
 	}
 	return
 }
 
 func (c *deployment) GetStatus(namespace string, deploymentName string) (status *DeploymentStatus, err error) {
+	return c.GetStatusContext(context.Background(), namespace, deploymentName)
+}
+
+func (c *deployment) GetStatusContext(ctx context.Context, namespace string, deploymentName string) (status *DeploymentStatus, err error) {
 	if c.err != nil {
 		return nil, c.err
 	}
-	deployment, err := c.client.AppsV1().Deployments(namespace).Get(deploymentName, metav1.GetOptions{})
+	var deployment *v1.Deployment
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		deployment, err = c.client.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		return
+	})
 	if err != nil {
+		err = c.wrapErr(err, namespace, deploymentName)
 		return
 	}
+	status = new(DeploymentStatus)
 	status.Replicas = int(deployment.Status.Replicas)
 	status.AvailableReplicas = int(deployment.Status.AvailableReplicas)
 	status.UnavailableReplicas = int(deployment.Status.UnavailableReplicas)
 	status.UpdatedReplicas = int(deployment.Status.UpdatedReplicas)
 	status.ReadyReplicas = int(deployment.Status.ReadyReplicas)
 	conditions := deployment.Status.Conditions
+	if len(conditions) == 0 {
+		return
+	}
 	status.ConditionType = string(conditions[len(conditions)-1].Type)
 	status.ConditionStatus = string(conditions[len(conditions)-1].Status)
 	status.LastUpdateTime = conditions[len(conditions)-1].LastUpdateTime.Format("2006-01-02 15:04:05")
@@ -88,69 +156,239 @@ func (c *deployment) GetStatus(namespace string, deploymentName string) (status
 }
 
 func (c *deployment) Create(input string) (err error) {
+	return c.CreateContext(context.Background(), input)
+}
+
+func (c *deployment) CreateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	deployment := new(v1.Deployment)
 	err = yaml.Unmarshal([]byte(input), deployment)
 	if err != nil {
-		return
-	}
-	// output for debug
-	_, err = yaml.Marshal(deployment)
-	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := deployment.Namespace
-	_, err = c.client.AppsV1().Deployments(namespace).Create(deployment)
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.client.AppsV1().Deployments(namespace).Create(ctx, deployment, metav1.CreateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, deployment.Name)
 	}
 	return
 }
 
 func (c *deployment) Delete(namespace string, deploymentName string) (err error) {
+	return c.DeleteContext(context.Background(), namespace, deploymentName)
+}
+
+func (c *deployment) DeleteContext(ctx context.Context, namespace string, deploymentName string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	err = c.client.AppsV1().Deployments(namespace).Delete(deploymentName, &metav1.DeleteOptions{})
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.client.AppsV1().Deployments(namespace).Delete(ctx, deploymentName, metav1.DeleteOptions{})
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, deploymentName)
 	}
 	return
 }
 
 func (c *deployment) Update(input string) (err error) {
+	return c.UpdateContext(context.Background(), input)
+}
+
+func (c *deployment) UpdateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	deployment := new(v1.Deployment)
 	err = yaml.Unmarshal([]byte(input), deployment)
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
-	// output for debug
-	_, err = yaml.Marshal(deployment)
+	namespace := deployment.Namespace
+	conflict := false
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		if conflict {
+			// reapply our object on top of the live resourceVersion
+			current, err := c.client.AppsV1().Deployments(namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			deployment.ResourceVersion = current.ResourceVersion
+		}
+		_, err := c.client.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+		conflict = retry.IsConflict(err)
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, deployment.Name)
+	}
+	return
+}
+
+func (c *deployment) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates the deployment or brings it in line with input, fields input leaves out are kept
+func (c *deployment) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	deployment := new(v1.Deployment)
+	err = yaml.Unmarshal([]byte(input), deployment)
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	manifest, err := apply.Manifest(input, v1.SchemeGroupVersion.String(), "Deployment")
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := deployment.Namespace
-	_, err = c.client.AppsV1().Deployments(namespace).Update(deployment)
+	deployments := c.client.AppsV1().Deployments(namespace)
+	_, err = apply.Do(ctx, c.applyOptions, c.retryPolicy, apply.Target{
+		Manifest: manifest,
+		Schema: &v1.Deployment{},
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			_, err := deployments.Patch(ctx, deployment.Name, patchType, data, options)
+			return err
+		},
+		Get: func(ctx context.Context) ([]byte, error) {
+			current, err := deployments.Get(ctx, deployment.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(current)
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			object := new(v1.Deployment)
+			if err := json.Unmarshal(manifest, object); err != nil {
+				return err
+			}
+			_, err := deployments.Create(ctx, object, metav1.CreateOptions{})
+			return err
+		},
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, deployment.Name)
 	}
 	return
 }
 
 func (c *deployment) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *deployment) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	var deployment *v1.Deployment
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		deployment, err = c.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(deployment)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *deployment) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the deployment with a YAML or JSON body and returns the patched object
+func (c *deployment) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	deployment, err := c.client.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var deployment *v1.Deployment
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		deployment, err = c.client.AppsV1().Deployments(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
 	d, err := json.Marshal(deployment)
 	if err != nil {
 		return "", err
 	}
 	ret = string(d)
 	return
-}
\ No newline at end of file
+}
+
+func (c *deployment) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of deployments, options.Limit and options.Continue page through the rest
+func (c *deployment) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var deployments *v1.DeploymentList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		deployments, err = c.client.AppsV1().Deployments(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&deployments.ListMeta)
+	for i := range deployments.Items {
+		d, err := json.Marshal(&deployments.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *deployment) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of deployments lazily, one request per options.Limit items
+func (c *deployment) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to deployments until ctx is done, reconnecting and resuming on its own
+func (c *deployment) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.AppsV1().Deployments(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.AppsV1().Deployments(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
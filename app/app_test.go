@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const deploymentYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+  labels:
+    app: nginx
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.17
+`
+
+func newDeployment(name string, labels map[string]string) *v1.Deployment {
+	return &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "default",
+			Labels: labels,
+		},
+		Spec: v1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.17"}},
+				},
+			},
+		},
+	}
+}
+
+func TestDeploymentCreateGetUpdateDelete(t *testing.T) {
+	c := NewDeploymentForClient(fake.NewSimpleClientset())
+	c.SetClusterName("cluster1")
+	if err := c.Create(deploymentYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(deploymentYAML); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	ret, err := c.Get("default", "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := new(v1.Deployment)
+	if err := json.Unmarshal([]byte(ret), deployment); err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Name != "nginx" || *deployment.Spec.Replicas != 1 {
+		t.Errorf("Get returned %s/%d replicas, want nginx/1", deployment.Name, *deployment.Spec.Replicas)
+	}
+	deployment.Spec.Replicas = new(int32)
+	*deployment.Spec.Replicas = 3
+	d, _ := json.Marshal(deployment)
+	if err := c.Update(string(d)); err != nil {
+		t.Fatal(err)
+	}
+	ret, _ = c.Get("default", "nginx")
+	json.Unmarshal([]byte(ret), deployment)
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("replicas after Update = %d, want 3", *deployment.Spec.Replicas)
+	}
+	if err := c.Delete("default", "nginx"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Get("default", "nginx")
+	if !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	var e *k8serrors.Error
+	if !errors.As(err, &e) || e.Cluster != "cluster1" || e.ResourceType != resourceType || e.Name != "nginx" {
+		t.Errorf("Get after Delete: err = %#v, want a *errors.Error for cluster1 deployment nginx", err)
+	}
+}
+
+func TestDeploymentInvalidManifest(t *testing.T) {
+	c := NewDeploymentForClient(fake.NewSimpleClientset())
+	if err := c.Create("spec: [unclosed"); !errors.Is(err, k8serrors.ErrInvalidManifest) {
+		t.Errorf("Create: err = %v, want ErrInvalidManifest", err)
+	}
+}
+
+func TestDeploymentSetErr(t *testing.T) {
+	c := NewDeploymentForClient(fake.NewSimpleClientset())
+	want := errors.New("no client")
+	c.SetErr(want)
+	if _, err := c.Get("default", "nginx"); err != want {
+		t.Errorf("Get: err = %v, want the error set with SetErr", err)
+	}
+}
+
+func TestDeploymentTriggerAndStatus(t *testing.T) {
+	deployment := newDeployment("nginx", nil)
+	deployment.Status = v1.DeploymentStatus{
+		Replicas: 2,
+		ReadyReplicas: 1,
+		Conditions: []v1.DeploymentCondition{{
+			Type: v1.DeploymentProgressing,
+			Status: corev1.ConditionTrue,
+			Reason: "NewReplicaSetAvailable",
+		}},
+	}
+	c := NewDeploymentForClient(fake.NewSimpleClientset(deployment))
+	if err := c.Trigger("default", "nginx", "nginx", "1.19"); err != nil {
+		t.Fatal(err)
+	}
+	ret, _ := c.Get("default", "nginx")
+	current := new(v1.Deployment)
+	json.Unmarshal([]byte(ret), current)
+	if image := current.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.19" {
+		t.Errorf("image after Trigger = %s, want nginx:1.19", image)
+	}
+	status, err := c.GetStatus("default", "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Replicas != 2 || status.ReadyReplicas != 1 || status.Reason != "NewReplicaSetAvailable" {
+		t.Errorf("GetStatus = %+v", status)
+	}
+}
+
+func TestDeploymentPatch(t *testing.T) {
+	c := NewDeploymentForClient(fake.NewSimpleClientset(newDeployment("nginx", nil)))
+	ret, err := c.Patch("default", "nginx", types.MergePatchType, "metadata:\n  labels:\n    tier: web\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := new(v1.Deployment)
+	json.Unmarshal([]byte(ret), deployment)
+	if deployment.Labels["tier"] != "web" {
+		t.Errorf("labels after Patch = %v, want tier=web", deployment.Labels)
+	}
+	if _, err := c.Patch("default", "missing", types.MergePatchType, "{}"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Patch missing: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeploymentListAndIterate(t *testing.T) {
+	c := NewDeploymentForClient(fake.NewSimpleClientset(
+		newDeployment("nginx", map[string]string{"app": "nginx"}),
+		newDeployment("redis", map[string]string{"app": "redis"}),
+	))
+	page, err := c.List("default", list.Options{LabelSelector: "app=nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("List(app=nginx) returned %d items, want 1", len(page.Items))
+	}
+	it := c.Iterate("default", list.Options{})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Iterate returned %d items, want 2", count)
+	}
+}
+
+func TestDeploymentWatch(t *testing.T) {
+	c := NewDeploymentForClient(fake.NewSimpleClientset(newDeployment("nginx", nil)))
+	c.SetClusterName("cluster1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := c.Watch(ctx, "default", watch.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != watch.Added || event.Name != "nginx" || event.ClusterName != "cluster1" {
+			t.Errorf("first event = %+v, want Added nginx in cluster1", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event before the timeout")
+	}
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+type ApplyOperation string
+
+const (
+	ApplyCreated ApplyOperation = "created"
+	ApplyUpdated ApplyOperation = "updated"
+	ApplyFailed ApplyOperation = "failed"
+)
+
+// ApplyResult is the outcome of one document of a multi-document manifest
+type ApplyResult struct {
+	// Index is the position of the document in the manifest, starting at 0 and counting empty documents
+	Index 					int
+	Kind 					string
+	ResourceObjectType 		ResourceObjectType
+	Namespace 				string
+	Name 					string
+	Operation 				ApplyOperation
+	Err 					error
+}
+
+// manifestDocument is a single document split out of a manifest
+type manifestDocument struct {
+	index 					int
+	raw 					string
+	gvk 					schema.GroupVersionKind
+	namespace 				string
+	name 					string
+}
+
+// splitManifest splits a manifest on ---, empty and comment-only documents are skipped
+func splitManifest(input string) (documents []manifestDocument, err error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(input)))
+	for index := 0; ; index++ {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		d, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", index, err)
+		}
+		if len(bytes.TrimSpace(d)) == 0 || string(bytes.TrimSpace(d)) == "null" {
+			continue
+		}
+		var header struct {
+			metav1.TypeMeta 		`json:",inline"`
+			Metadata 				metav1.ObjectMeta 	`json:"metadata"`
+		}
+		if err := yaml.Unmarshal(raw, &header); err != nil {
+			return nil, fmt.Errorf("document %d: %w", index, err)
+		}
+		if len(header.Kind) == 0 {
+			return nil, fmt.Errorf("document %d: missing kind", index)
+		}
+		documents = append(documents, manifestDocument{
+			index: index,
+			raw: string(raw),
+			gvk: header.GroupVersionKind(),
+			namespace: header.Metadata.Namespace,
+			name: header.Metadata.Name,
+		})
+	}
+	return
+}
+
+// typedApplyGroups is the API group of every built-in type that applies documents itself.
+// Pod and Event have no typed apply and go to the dynamic client like any other kind.
+var typedApplyGroups = map[ResourceObjectType]string{
+	KubernetesService: "",
+	KubernetesConfigMap: "",
+	KubernetesSecret: "",
+	KubernetesDeployment: "apps",
+	KubernetesJob: "batch",
+}
+
+// resourceObjectTypeForKind maps a kind to a registered type, e.g. ConfigMap to configMap.
+// A built-in type is only used for its own API group, so serving.knative.dev/v1 Service is not a service,
+// the rest, Pod and Event included, are addressed as Kind or Kind.version.group for the dynamic client.
+func resourceObjectTypeForKind(gvk schema.GroupVersionKind) ResourceObjectType {
+	runes := []rune(gvk.Kind)
+	runes[0] = unicode.ToLower(runes[0])
+	t := ResourceObjectType(runes)
+	group, ok := typedApplyGroups[t]
+	switch {
+	case ok && group == gvk.Group:
+		return t
+	case !ok && t != KubernetesPod && t != KubernetesEvent && isRegisteredResourceObjectType(t):
+		// a type registered with RegisterResourceObject is taken to own its kind
+		return t
+	}
+	if len(gvk.Group) == 0 {
+		return ResourceObjectType(gvk.Kind)
+	}
+	return ResourceObjectType(gvk.Kind + "." + gvk.Version + "." + gvk.Group)
+}
+
+func isRegisteredResourceObjectType(resourceObjectType ResourceObjectType) bool {
+	_, ok := resourceObjectFactory(resourceObjectType)
+	return ok
+}
+
+func (k *k8sClient) Apply(input string) (results []ApplyResult, err error) {
+	return k.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates or updates every document of a multi-document manifest in order,
+// each through the resource object registered for its kind. A failed document does not stop
+// the rest, err then tells how many failed and results has the details.
+func (k *k8sClient) ApplyContext(ctx context.Context, input string) (results []ApplyResult, err error) {
+	documents, err := splitManifest(input)
+	if err != nil {
+		return nil, k8serrors.InvalidManifest(err, k.clusterName, "")
+	}
+	failed := 0
+	for _, document := range documents {
+		result := ApplyResult{
+			Index: document.index,
+			Kind: document.gvk.Kind,
+			ResourceObjectType: resourceObjectTypeForKind(document.gvk),
+			Namespace: document.namespace,
+			Name: document.name,
+		}
+		result.Operation, result.Err = k.applyDocument(ctx, result.ResourceObjectType, document.raw)
+		if result.Err != nil {
+			failed++
+		}
+		results = append(results, result)
+	}
+	if failed != 0 {
+		err = fmt.Errorf("cluster %s: %d of %d objects failed to apply", k.clusterName, failed, len(results))
+	}
+	return
+}
+
+// applyDocument creates or updates the object through the resource object's Apply,
+// the apply report tells which of the two happened
+func (k *k8sClient) applyDocument(ctx context.Context, resourceObjectType ResourceObjectType, document string) (operation ApplyOperation, err error) {
+	ctx, report := apply.WithReport(ctx)
+	if err = k.CommonResourceObject(resourceObjectType).ApplyContext(ctx, document); err != nil {
+		return ApplyFailed, err
+	}
+	if report.Created() != 0 {
+		return ApplyCreated, nil
+	}
+	return ApplyUpdated, nil
+}
@@ -0,0 +1,256 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultFieldManager = "kubernetes-client"
+	// LastAppliedAnnotation is where the client-side fallback keeps the applied manifest, like kubectl apply
+	LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+type Options struct {
+	// FieldManager owns the applied fields on the server, default "kubernetes-client"
+	FieldManager 			string
+	// Force takes over fields owned by other managers instead of failing with a conflict
+	Force 					bool
+}
+
+// Target is one object to apply, the resource packages fill in the calls for their kind
+type Target struct {
+	// Manifest is the object as JSON, including apiVersion and kind
+	Manifest 				[]byte
+	// Schema is an empty typed object, e.g. &appsv1.Deployment{}, used for strategic merge patches.
+	// Nil falls back to JSON merge patches, as for custom resources.
+	Schema 					interface{}
+	Patch 					func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error
+	// Get returns the live object as JSON
+	Get 					func(ctx context.Context) (current []byte, err error)
+	Create 					func(ctx context.Context, manifest []byte) error
+}
+
+// Report counts the objects created and updated by the Do calls sharing its context
+type Report struct {
+	mu 						sync.Mutex
+	created 				int
+	updated 				int
+}
+
+func (r *Report) Created() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.created
+}
+
+func (r *Report) Updated() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updated
+}
+
+func (r *Report) add(created bool) {
+	r.mu.Lock()
+	if created {
+		r.created++
+	} else {
+		r.updated++
+	}
+	r.mu.Unlock()
+}
+
+type optionsKey struct{}
+
+type reportKey struct{}
+
+// WithOptions overrides the client's apply options for calls made with the returned context
+func WithOptions(ctx context.Context, options Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, options)
+}
+
+// WithReport returns a context whose Do calls record into report whether they created or updated their object
+func WithReport(ctx context.Context) (context.Context, *Report) {
+	report := new(Report)
+	return context.WithValue(ctx, reportKey{}, report), report
+}
+
+// resolve picks the options from ctx, else clientOptions, else the defaults
+func resolve(ctx context.Context, clientOptions *Options) (options Options) {
+	if clientOptions != nil {
+		options = *clientOptions
+	}
+	if o, ok := ctx.Value(optionsKey{}).(Options); ok {
+		options = o
+	}
+	if len(options.FieldManager) == 0 {
+		options.FieldManager = DefaultFieldManager
+	}
+	return
+}
+
+// Manifest converts YAML or JSON input to JSON, apiVersion and kind are set when the input leaves them out
+func Manifest(input string, apiVersion string, kind string) (manifest []byte, err error) {
+	d, err := yaml.YAMLToJSON([]byte(input))
+	if err != nil {
+		return
+	}
+	object := make(map[string]interface{})
+	if err = json.Unmarshal(d, &object); err != nil {
+		return
+	}
+	if _, ok := object["apiVersion"]; !ok {
+		object["apiVersion"] = apiVersion
+	}
+	if _, ok := object["kind"]; !ok {
+		object["kind"] = kind
+	}
+	return json.Marshal(object)
+}
+
+// Do applies the target with server-side apply, servers without it get a client-side
+// three-way merge against the last applied manifest instead. created tells whether the object
+// did not exist before. Only the GET and the apply patch are retried on transient errors, the
+// fallback's create and patch are retried like other non-idempotent requests.
+func Do(ctx context.Context, clientOptions *Options, policy *retry.Policy, target Target) (created bool, err error) {
+	options := resolve(ctx, clientOptions)
+	var current []byte
+	unsupported := false
+	// a 409 here is a field owned by another manager, sending the same patch again cannot succeed
+	err = retry.Do(ctx, policy, false, func() (err error) {
+		// the apply patch answers alike whether it created the object or not, so look first
+		current, err = target.Get(ctx)
+		switch {
+		case apierrors.IsNotFound(err):
+			created, current = true, nil
+		case err != nil:
+			return err
+		default:
+			created = false
+		}
+		force := options.Force
+		err = target.Patch(ctx, types.ApplyPatchType, target.Manifest, metav1.PatchOptions{
+			FieldManager: options.FieldManager,
+			Force: &force,
+		})
+		unsupported = isUnsupported(err)
+		if unsupported {
+			return nil
+		}
+		return err
+	})
+	if err == nil && unsupported {
+		err = retry.DoNonIdempotent(ctx, policy, func() error {
+			return clientSideApply(ctx, options, target, current)
+		})
+	}
+	if err != nil {
+		return false, err
+	}
+	if report, ok := ctx.Value(reportKey{}).(*Report); ok {
+		report.add(created)
+	}
+	return
+}
+
+// isUnsupported tells servers older than 1.16, or with the ServerSideApply feature gate off,
+// which reject the apply patch type
+func isUnsupported(err error) bool {
+	var status apierrors.APIStatus
+	if err == nil || !errors.As(err, &status) {
+		return false
+	}
+	statusErr, ok := status.(error)
+	return ok && (apierrors.IsUnsupportedMediaType(statusErr) || apierrors.IsMethodNotSupported(statusErr))
+}
+
+// clientSideApply creates the object when current is nil, else patches it from current
+func clientSideApply(ctx context.Context, options Options, target Target, current []byte) (err error) {
+	modified, err := withLastApplied(target.Manifest)
+	if err != nil {
+		return
+	}
+	if current == nil {
+		return target.Create(ctx, modified)
+	}
+	original, err := lastApplied(current)
+	if err != nil {
+		return
+	}
+	var (
+		patch []byte
+		patchType types.PatchType
+	)
+	if target.Schema != nil {
+		schema, err := strategicpatch.NewPatchMetaFromStruct(target.Schema)
+		if err != nil {
+			return err
+		}
+		patch, err = strategicpatch.CreateThreeWayMergePatch(original, modified, current, schema, true)
+		if err != nil {
+			return fmt.Errorf("create strategic merge patch: %w", err)
+		}
+		patchType = types.StrategicMergePatchType
+	} else {
+		patch, err = jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+		if err != nil {
+			return fmt.Errorf("create merge patch: %w", err)
+		}
+		patchType = types.MergePatchType
+	}
+	if string(patch) == "{}" {
+		return
+	}
+	return target.Patch(ctx, patchType, patch, metav1.PatchOptions{
+		FieldManager: options.FieldManager,
+	})
+}
+
+// withLastApplied records the manifest in its own last applied annotation
+func withLastApplied(manifest []byte) (modified []byte, err error) {
+	object := make(map[string]interface{})
+	if err = json.Unmarshal(manifest, &object); err != nil {
+		return
+	}
+	metadata, _ := object["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+		object["metadata"] = metadata
+	}
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = make(map[string]interface{})
+		metadata["annotations"] = annotations
+	}
+	delete(annotations, LastAppliedAnnotation)
+	applied, err := json.Marshal(object)
+	if err != nil {
+		return
+	}
+	annotations[LastAppliedAnnotation] = string(applied)
+	return json.Marshal(object)
+}
+
+func lastApplied(current []byte) (original []byte, err error) {
+	var object struct {
+		Metadata 			metav1.ObjectMeta 	`json:"metadata"`
+	}
+	if err = json.Unmarshal(current, &object); err != nil {
+		return
+	}
+	if applied, ok := object.Metadata.Annotations[LastAppliedAnnotation]; ok {
+		return []byte(applied), nil
+	}
+	// never applied before, the three-way merge then only adds and changes fields
+	return []byte("{}"), nil
+}
@@ -0,0 +1,231 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var testPolicy = retry.Policy{
+	MaxAttempts: 3,
+	InitialBackoff: time.Millisecond,
+	Multiplier: 1,
+}
+
+type patchCall struct {
+	patchType 				types.PatchType
+	data 					string
+	options 				metav1.PatchOptions
+}
+
+// fakeTarget records the calls Do makes, getErrs and patchErrs are returned by the first calls in turn
+type fakeTarget struct {
+	current 				[]byte
+	getErrs 				[]error
+	patchErrs 				[]error
+	createErr 				error
+	gets 					int
+	patches 				[]patchCall
+	creates 				[]string
+}
+
+func (f *fakeTarget) target(manifest string) Target {
+	return Target{
+		Manifest: []byte(manifest),
+		Get: func(ctx context.Context) ([]byte, error) {
+			f.gets++
+			if len(f.getErrs) != 0 {
+				err := f.getErrs[0]
+				f.getErrs = f.getErrs[1:]
+				return nil, err
+			}
+			if f.current == nil {
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "settings")
+			}
+			return f.current, nil
+		},
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			f.patches = append(f.patches, patchCall{patchType, string(data), options})
+			if len(f.patchErrs) != 0 {
+				err := f.patchErrs[0]
+				f.patchErrs = f.patchErrs[1:]
+				return err
+			}
+			return nil
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			f.creates = append(f.creates, string(manifest))
+			return f.createErr
+		},
+	}
+}
+
+// the status an API server without server-side apply answers an apply patch with
+func unsupportedMediaType() error {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code: http.StatusUnsupportedMediaType,
+		Reason: metav1.StatusReasonUnsupportedMediaType,
+	}}
+}
+
+const configMapManifest = `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"default"},"data":{"level":"info"}}`
+
+func TestDoServerSideApply(t *testing.T) {
+	f := &fakeTarget{}
+	ctx, report := WithReport(context.Background())
+	created, err := Do(ctx, &Options{Force: true}, nil, f.target(configMapManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || report.Created() != 1 || report.Updated() != 0 {
+		t.Errorf("created = %v, report = %d created %d updated, want a create", created, report.Created(), report.Updated())
+	}
+	if len(f.patches) != 1 {
+		t.Fatalf("%d patches, want 1", len(f.patches))
+	}
+	call := f.patches[0]
+	if call.patchType != types.ApplyPatchType || call.data != configMapManifest {
+		t.Errorf("patch = %s %s, want the manifest as apply patch", call.patchType, call.data)
+	}
+	if call.options.FieldManager != DefaultFieldManager || call.options.Force == nil || !*call.options.Force {
+		t.Errorf("patch options = %+v, want the default field manager and force", call.options)
+	}
+
+	f.current = []byte(configMapManifest)
+	ctx = WithOptions(ctx, Options{FieldManager: "deployer"})
+	created, err = Do(ctx, &Options{Force: true}, nil, f.target(configMapManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || report.Updated() != 1 {
+		t.Errorf("created = %v, report = %d updated, want an update", created, report.Updated())
+	}
+	if call := f.patches[1]; call.options.FieldManager != "deployer" || *call.options.Force {
+		t.Errorf("patch options = %+v, want the context options to replace the client options", call.options)
+	}
+}
+
+func TestDoRetriesGetAndApplyPatch(t *testing.T) {
+	f := &fakeTarget{
+		getErrs: []error{apierrors.NewServiceUnavailable("apiserver restarting")},
+		patchErrs: []error{apierrors.NewInternalError(errors.New("etcd leader changed"))},
+	}
+	if _, err := Do(context.Background(), nil, &testPolicy, f.target(configMapManifest)); err != nil {
+		t.Fatal(err)
+	}
+	if f.gets != 3 || len(f.patches) != 2 {
+		t.Errorf("%d gets and %d patches, want 3 and 2", f.gets, len(f.patches))
+	}
+}
+
+func TestDoDoesNotRetryFieldConflicts(t *testing.T) {
+	f := &fakeTarget{
+		current: []byte(configMapManifest),
+		patchErrs: []error{apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "settings", errors.New("field owned by kubectl"))},
+	}
+	if _, err := Do(context.Background(), nil, &testPolicy, f.target(configMapManifest)); !apierrors.IsConflict(err) {
+		t.Errorf("err = %v, want the conflict", err)
+	}
+	if len(f.patches) != 1 {
+		t.Errorf("%d patches, want 1", len(f.patches))
+	}
+}
+
+func TestDoClientSideCreate(t *testing.T) {
+	f := &fakeTarget{
+		patchErrs: []error{unsupportedMediaType()},
+	}
+	created, err := Do(context.Background(), nil, &testPolicy, f.target(configMapManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || len(f.creates) != 1 {
+		t.Fatalf("created = %v after %d creates, want one create", created, len(f.creates))
+	}
+	object := new(metav1.PartialObjectMetadata)
+	json.Unmarshal([]byte(f.creates[0]), object)
+	var applied struct {
+		Kind 				string 				`json:"kind"`
+		Data 				map[string]string 	`json:"data"`
+	}
+	if err := json.Unmarshal([]byte(object.Annotations[LastAppliedAnnotation]), &applied); err != nil || applied.Kind != "ConfigMap" || applied.Data["level"] != "info" {
+		t.Errorf("last applied = %s, want the manifest", object.Annotations[LastAppliedAnnotation])
+	}
+}
+
+// the server may have stored the object before failing, the fallback must not create it twice
+func TestDoClientSideCreateIsNotRetriedOnServerErrors(t *testing.T) {
+	f := &fakeTarget{
+		patchErrs: []error{unsupportedMediaType()},
+		createErr: apierrors.NewInternalError(errors.New("etcd timeout")),
+	}
+	if _, err := Do(context.Background(), nil, &testPolicy, f.target(configMapManifest)); err == nil {
+		t.Fatal("Do succeeded, want the internal error")
+	}
+	if len(f.creates) != 1 || f.gets != 1 {
+		t.Errorf("%d creates after %d gets, want 1 and 1", len(f.creates), f.gets)
+	}
+	f = &fakeTarget{
+		patchErrs: []error{unsupportedMediaType()},
+		createErr: apierrors.NewTooManyRequests("slow down", 0),
+	}
+	if _, err := Do(context.Background(), nil, &testPolicy, f.target(configMapManifest)); err == nil {
+		t.Fatal("Do succeeded, want the throttling error")
+	}
+	// 429 is sent before the server acts, so it is safe to try again
+	if len(f.creates) != testPolicy.MaxAttempts {
+		t.Errorf("%d creates, want %d", len(f.creates), testPolicy.MaxAttempts)
+	}
+}
+
+func TestDoClientSidePatch(t *testing.T) {
+	applied := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"default"},"data":{"level":"debug","trace":"on"}}`
+	current, _ := withLastApplied([]byte(applied))
+	f := &fakeTarget{
+		current: current,
+		patchErrs: []error{unsupportedMediaType()},
+	}
+	created, err := Do(context.Background(), nil, nil, f.target(configMapManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || len(f.creates) != 0 || len(f.patches) != 2 {
+		t.Fatalf("created = %v, %d creates, %d patches, want one apply and one merge patch", created, len(f.creates), len(f.patches))
+	}
+	call := f.patches[1]
+	if call.patchType != types.MergePatchType {
+		t.Errorf("fallback patch type = %s, want %s without a schema", call.patchType, types.MergePatchType)
+	}
+	// trace was applied before and is gone from the manifest, so the merge removes it
+	if !strings.Contains(call.data, `"trace":null`) || !strings.Contains(call.data, `"level":"info"`) {
+		t.Errorf("fallback patch = %s, want level set to info and trace removed", call.data)
+	}
+}
+
+func TestDoClientSideStrategicMergePatch(t *testing.T) {
+	manifest := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"nginx"},"spec":{"replicas":3}}`
+	current, _ := withLastApplied([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"nginx"},"spec":{"replicas":1}}`))
+	f := &fakeTarget{
+		current: current,
+		patchErrs: []error{unsupportedMediaType()},
+	}
+	target := f.target(manifest)
+	target.Schema = &appsv1.Deployment{}
+	if _, err := Do(context.Background(), nil, nil, target); err != nil {
+		t.Fatal(err)
+	}
+	if call := f.patches[1]; call.patchType != types.StrategicMergePatchType || !strings.Contains(call.data, `"replicas":3`) {
+		t.Errorf("fallback patch = %s %s, want a strategic merge setting replicas to 3", call.patchType, call.data)
+	}
+}
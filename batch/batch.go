@@ -1,15 +1,30 @@
 package batch
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	batchv1 "k8s.io/api/batch/v1"
 	"sigs.k8s.io/yaml"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
 	"encoding/json"
 )
 
+const resourceType = "job"
+
 type job struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
 	err 					error
 }
 
@@ -18,11 +33,25 @@ type Job interface {
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
 	GetStatus(namespace string, jobName string) (status *batchv1.JobStatus, err error)
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
+	GetStatusContext(ctx context.Context, namespace string, jobName string) (status *batchv1.JobStatus, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *job {
+func NewForClient(client kubernetes.Interface) *job {
 	return &job{
 		client: client,
 	}
@@ -32,62 +61,161 @@ func (c *job) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *job) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors and update conflicts, nil sends every request once
+func (c *job) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+// SetApplyOptions sets the field manager and force flag of Apply, nil uses the defaults
+func (c *job) SetApplyOptions(options *apply.Options) {
+	c.applyOptions = options
+}
+
+func (c *job) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *job) Create(input string) (err error) {
+	return c.CreateContext(context.Background(), input)
+}
+
+func (c *job) CreateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	job := new(batchv1.Job)
 	err = yaml.Unmarshal([]byte(input), job)
 	if err != nil {
-		return
-	}
-	// output for debug
-	_, err = yaml.Marshal(job)
-	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := job.Namespace
-	_, err = c.client.BatchV1().Jobs(namespace).Create(job)
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.client.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, job.Name)
 	}
 	return
 }
 
 func (c *job) Delete(namespace string, name string) (err error) {
+	return c.DeleteContext(context.Background(), namespace, name)
+}
+
+func (c *job) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	return c.client.BatchV1().Jobs(namespace).Delete(name, &metav1.DeleteOptions{})
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.client.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	})
+	return c.wrapErr(err, namespace, name)
 }
 
 func (c *job) Update(input string) (err error) {
+	return c.UpdateContext(context.Background(), input)
+}
+
+func (c *job) UpdateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	job := new(batchv1.Job)
 	err = yaml.Unmarshal([]byte(input), job)
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	namespace := job.Namespace
+	conflict := false
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		if conflict {
+			// reapply our object on top of the live resourceVersion
+			current, err := c.client.BatchV1().Jobs(namespace).Get(ctx, job.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			job.ResourceVersion = current.ResourceVersion
+		}
+		_, err := c.client.BatchV1().Jobs(namespace).Update(ctx, job, metav1.UpdateOptions{})
+		conflict = retry.IsConflict(err)
+		return err
+	})
+	if err != nil {
+		return c.wrapErr(err, namespace, job.Name)
+	}
+	return
+}
+
+func (c *job) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates the job or brings it in line with input, fields input leaves out are kept
+func (c *job) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	job := new(batchv1.Job)
+	err = yaml.Unmarshal([]byte(input), job)
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
-	// output for debug
-	_, err = yaml.Marshal(job)
+	manifest, err := apply.Manifest(input, batchv1.SchemeGroupVersion.String(), "Job")
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := job.Namespace
-	_, err = c.client.BatchV1().Jobs(namespace).Update(job)
+	jobs := c.client.BatchV1().Jobs(namespace)
+	_, err = apply.Do(ctx, c.applyOptions, c.retryPolicy, apply.Target{
+		Manifest: manifest,
+		Schema: &batchv1.Job{},
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			_, err := jobs.Patch(ctx, job.Name, patchType, data, options)
+			return err
+		},
+		Get: func(ctx context.Context) ([]byte, error) {
+			current, err := jobs.Get(ctx, job.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(current)
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			object := new(batchv1.Job)
+			if err := json.Unmarshal(manifest, object); err != nil {
+				return err
+			}
+			_, err := jobs.Create(ctx, object, metav1.CreateOptions{})
+			return err
+		},
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, job.Name)
 	}
 	return
 }
 
 func (c *job) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *job) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	job, err := c.client.BatchV1().Jobs(namespace).Get(name, metav1.GetOptions{})
+	var job *batchv1.Job
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		job, err = c.client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
 	d, err := json.Marshal(job)
 	if err != nil {
 		return "", err
@@ -96,13 +224,111 @@ func (c *job) Get(namespace string, name string) (ret string, err error) {
 	return
 }
 
+func (c *job) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the job with a YAML or JSON body and returns the patched object
+func (c *job) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var job *batchv1.Job
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		job, err = c.client.BatchV1().Jobs(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *job) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of jobs, options.Limit and options.Continue page through the rest
+func (c *job) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var jobs *batchv1.JobList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		jobs, err = c.client.BatchV1().Jobs(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&jobs.ListMeta)
+	for i := range jobs.Items {
+		d, err := json.Marshal(&jobs.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *job) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of jobs lazily, one request per options.Limit items
+func (c *job) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to jobs until ctx is done, reconnecting and resuming on its own
+func (c *job) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.BatchV1().Jobs(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.BatchV1().Jobs(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
+
 func (c *job) GetStatus(namespace string, jobName string) (status *batchv1.JobStatus, err error) {
+	return c.GetStatusContext(context.Background(), namespace, jobName)
+}
+
+func (c *job) GetStatusContext(ctx context.Context, namespace string, jobName string) (status *batchv1.JobStatus, err error) {
 	if c.err != nil {
 		return nil, c.err
 	}
-	job, err := c.client.BatchV1().Jobs(namespace).Get(jobName, metav1.GetOptions{})
+	var job *batchv1.Job
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		job, err = c.client.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+		return
+	})
 	if err != nil {
-		return nil, err
+		return nil, c.wrapErr(err, namespace, jobName)
 	}
 	return &job.Status, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,119 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const jobYAML = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  namespace: default
+spec:
+  backoffLimit: 2
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: migrate
+        image: migrate:1.0
+`
+
+func TestJobCreateGetUpdateDelete(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	c.SetClusterName("cluster1")
+	if err := c.Create(jobYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(jobYAML); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	ret, err := c.Get("default", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := new(batchv1.Job)
+	if err := json.Unmarshal([]byte(ret), job); err != nil {
+		t.Fatal(err)
+	}
+	*job.Spec.BackoffLimit = 4
+	d, _ := json.Marshal(job)
+	if err := c.Update(string(d)); err != nil {
+		t.Fatal(err)
+	}
+	ret, _ = c.Get("default", "migrate")
+	json.Unmarshal([]byte(ret), job)
+	if *job.Spec.BackoffLimit != 4 {
+		t.Errorf("backoffLimit after Update = %d, want 4", *job.Spec.BackoffLimit)
+	}
+	if err := c.Delete("default", "migrate"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("default", "migrate"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := c.Delete("default", "migrate"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestJobGetStatus(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "migrate",
+			Namespace: "default",
+		},
+		Status: batchv1.JobStatus{
+			Succeeded: 1,
+		},
+	}))
+	status, err := c.GetStatus("default", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Succeeded != 1 {
+		t.Errorf("Succeeded = %d, want 1", status.Succeeded)
+	}
+	if _, err := c.GetStatus("default", "missing"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("GetStatus missing: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestJobPatchAndList(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	if err := c.Create(jobYAML); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := c.Patch("default", "migrate", types.MergePatchType, `{"metadata":{"labels":{"run":"nightly"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := new(batchv1.Job)
+	json.Unmarshal([]byte(ret), job)
+	if job.Labels["run"] != "nightly" {
+		t.Errorf("labels after Patch = %v, want run=nightly", job.Labels)
+	}
+	page, err := c.List("default", list.Options{LabelSelector: "run=nightly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("List(run=nightly) returned %d items, want 1", len(page.Items))
+	}
+	page, err = c.List("other", list.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("List(other) returned %d items, want 0", len(page.Items))
+	}
+}
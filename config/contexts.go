@@ -0,0 +1,145 @@
+package config
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const kubeConfigEnv = "KUBECONFIG"
+
+// ContextFilter selects kubeconfig contexts by glob pattern. The syntax is path.Match's except
+// that * and ? also match /, so "*prod" matches arn:aws:eks:eu-west-1:123:cluster/prod.
+// An empty Include matches every context, Exclude wins over Include.
+type ContextFilter struct {
+	Include 				[]string
+	Exclude 				[]string
+}
+
+type ContextKubeConfig struct {
+	ContextName 			string
+	KubeConfig 				*rest.Config
+}
+
+func (f *ContextFilter) Match(contextName string) (ok bool, err error) {
+	if f == nil {
+		return true, nil
+	}
+	for _, pattern := range f.Exclude {
+		matched, err := matchContext(pattern, contextName)
+		if err != nil {
+			return false, fmt.Errorf("invalid context pattern %q: %s", pattern, err)
+		}
+		if matched {
+			return false, nil
+		}
+	}
+	if len(f.Include) == 0 {
+		return true, nil
+	}
+	for _, pattern := range f.Include {
+		matched, err := matchContext(pattern, contextName)
+		if err != nil {
+			return false, fmt.Errorf("invalid context pattern %q: %s", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchContext matches a whole context name against a glob whose * and ? cross /
+func matchContext(pattern string, contextName string) (matched bool, err error) {
+	var expr strings.Builder
+	expr.WriteString("^(?s:")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return false, path.ErrBadPattern
+			}
+			expr.WriteString(regexp.QuoteMeta(pattern[i:i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end <= 0 {
+				return false, path.ErrBadPattern
+			}
+			expr.WriteString(pattern[i:i+end+2])
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i:i+1]))
+		}
+	}
+	expr.WriteString(")$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return false, path.ErrBadPattern
+	}
+	return re.MatchString(contextName), nil
+}
+
+// KubeConfigPathsFromEnv returns the files listed in KUBECONFIG, falling back to ~/.kube/config
+func KubeConfigPathsFromEnv() []string {
+	var paths []string
+	for _, p := range filepath.SplitList(os.Getenv(kubeConfigEnv)) {
+		if len(p) != 0 {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, filepath.Join(homeDir(), clientcmd.RecommendedHomeDir, clientcmd.RecommendedFileName))
+	}
+	return paths
+}
+
+// ReadKubeConfigContexts merges the kubeconfig files the same way kubectl merges KUBECONFIG
+// and returns one rest config per context accepted by filter, sorted by context name
+func ReadKubeConfigContexts(kubeConfigPaths []string, filter *ContextFilter) (configs []ContextKubeConfig, err error) {
+	if len(kubeConfigPaths) == 0 {
+		err = fmt.Errorf("empty kubeconfig paths provided")
+		return
+	}
+	rules := &clientcmd.ClientConfigLoadingRules{
+		Precedence: kubeConfigPaths,
+	}
+	rawConfig, err := rules.Load()
+	if err != nil {
+		err = k8serrors.InvalidKubeConfig(err, strings.Join(kubeConfigPaths, string(filepath.ListSeparator)))
+		return
+	}
+	var contextNames []string
+	for name := range rawConfig.Contexts {
+		ok, err := filter.Match(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			contextNames = append(contextNames, name)
+		}
+	}
+	sort.Strings(contextNames)
+	for _, name := range contextNames {
+		kubeConfig, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, name, &clientcmd.ConfigOverrides{}, rules).ClientConfig()
+		if err != nil {
+			return nil, k8serrors.InvalidKubeConfig(fmt.Errorf("context %s: %w", name, err), strings.Join(kubeConfigPaths, string(filepath.ListSeparator)))
+		}
+		configs = append(configs, ContextKubeConfig{
+			ContextName: name,
+			KubeConfig: kubeConfig,
+		})
+	}
+	return
+}
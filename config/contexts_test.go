@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextFilterMatch(t *testing.T) {
+	eksProd := "arn:aws:eks:eu-west-1:123:cluster/prod"
+	tests := []struct {
+		filter 				*ContextFilter
+		contextName 		string
+		want 				bool
+	}{
+		{nil, "anything", true},
+		{&ContextFilter{}, "anything", true},
+		{&ContextFilter{Include: []string{"*prod"}}, eksProd, true},
+		{&ContextFilter{Include: []string{"arn:aws:eks:*:123:cluster/*"}}, eksProd, true},
+		{&ContextFilter{Include: []string{"prod-??"}}, "prod-eu", true},
+		{&ContextFilter{Include: []string{"prod-??"}}, "prod-eu2", false},
+		{&ContextFilter{Include: []string{"prod-[eu]*"}}, "prod-us", true},
+		{&ContextFilter{Include: []string{"prod-[^eu]*"}}, "prod-us", false},
+		{&ContextFilter{Include: []string{"prod"}}, "prod-eu", false},
+		{&ContextFilter{Include: []string{"kind.local"}}, "kindxlocal", false},
+		{&ContextFilter{Include: []string{`prod\*`}}, "prod*", true},
+		{&ContextFilter{Include: []string{`prod\*`}}, "prod-eu", false},
+		{&ContextFilter{Include: []string{"*prod*"}, Exclude: []string{"*/prod"}}, eksProd, false},
+		{&ContextFilter{Include: []string{"*prod*"}, Exclude: []string{"*/prod"}}, "prod-eu", true},
+		{&ContextFilter{Exclude: []string{"minikube"}}, "minikube", false},
+		{&ContextFilter{Exclude: []string{"minikube"}}, "kind", true},
+	}
+	for _, test := range tests {
+		ok, err := test.filter.Match(test.contextName)
+		if err != nil {
+			t.Errorf("%+v.Match(%s): %v", test.filter, test.contextName, err)
+			continue
+		}
+		if ok != test.want {
+			t.Errorf("%+v.Match(%s) = %v, want %v", test.filter, test.contextName, ok, test.want)
+		}
+	}
+	for _, pattern := range []string{"prod-[eu", `prod\`, "[]"} {
+		if _, err := (&ContextFilter{Include: []string{pattern}}).Match("prod-eu"); err == nil {
+			t.Errorf("pattern %q: err = nil, want an invalid pattern error", pattern)
+		}
+	}
+}
+
+const kubeConfigTemplate = `apiVersion: v1
+kind: Config
+current-context: %[1]s
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: %[1]s-token
+`
+
+func kubeConfig(contextName string, server string) string {
+	return fmt.Sprintf(kubeConfigTemplate, contextName, server)
+}
+
+// TestReadKubeConfigContextsMerge checks the KUBECONFIG rules: every file adds its contexts
+// and the first file to define a context name wins
+func TestReadKubeConfigContextsMerge(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	writeFile(t, first, kubeConfig("prod", "https://prod-first:6443"))
+	writeFile(t, second, kubeConfig("prod", "https://prod-second:6443"))
+	third := filepath.Join(dir, "third")
+	writeFile(t, third, kubeConfig("test", "https://test:6443"))
+	configs, err := ReadKubeConfigContexts([]string{first, second, third}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("ReadKubeConfigContexts returned %d contexts, want 2", len(configs))
+	}
+	if configs[0].ContextName != "prod" || configs[0].KubeConfig.Host != "https://prod-first:6443" {
+		t.Errorf("contexts[0] = %s at %s, want prod at https://prod-first:6443", configs[0].ContextName, configs[0].KubeConfig.Host)
+	}
+	if configs[1].ContextName != "test" || configs[1].KubeConfig.BearerToken != "test-token" {
+		t.Errorf("contexts[1] = %s with token %s, want test with test-token", configs[1].ContextName, configs[1].KubeConfig.BearerToken)
+	}
+	configs, err = ReadKubeConfigContexts([]string{first, third}, &ContextFilter{Exclude: []string{"prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 || configs[0].ContextName != "test" {
+		t.Errorf("ReadKubeConfigContexts with Exclude prod = %+v, want only test", configs)
+	}
+	if _, err := ReadKubeConfigContexts(nil, nil); err == nil {
+		t.Error("no paths: err = nil")
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// FindKubeConfigFiles lists the regular files in dir whose base name matches pattern
+// (filepath.Match syntax, empty matches every file), sorted by path. Symlinks to regular files
+// are listed under the link's path, hidden files and directories such as ..data are skipped and
+// subdirectories are only searched when recursive is set.
+func FindKubeConfigFiles(dir string, recursive bool, pattern string) (paths []string, err error) {
+	if len(pattern) != 0 {
+		if _, err = filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig file pattern %q: %s", pattern, err)
+		}
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		hidden := strings.HasPrefix(info.Name(), ".") && path != dir
+		if info.IsDir() {
+			if path != dir && (!recursive || hidden) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden {
+			return nil
+		}
+		// mounted Secrets and ConfigMaps link every key to ..data/<key>, keep links to regular files
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(path)
+			if err != nil {
+				return nil
+			}
+			info = target
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if len(pattern) != 0 {
+			if matched, _ := filepath.Match(pattern, info.Name()); !matched {
+				return nil
+			}
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return
+}
+
+// ClusterNameFromFileName returns the base name without extension, e.g. prod-eu for /dir/prod-eu.yaml
+func ClusterNameFromFileName(path string) string {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// CurrentContextName returns the current-context of the kubeconfig file
+func CurrentContextName(kubeConfigPath string) (contextName string, err error) {
+	rawConfig, err := clientcmd.LoadFromFile(kubeConfigPath)
+	if err != nil {
+		return "", k8serrors.InvalidKubeConfig(err, kubeConfigPath)
+	}
+	if len(rawConfig.CurrentContext) == 0 {
+		return "", k8serrors.InvalidKubeConfig(fmt.Errorf("no current-context set"), kubeConfigPath)
+	}
+	return rawConfig.CurrentContext, nil
+}
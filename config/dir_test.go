@@ -0,0 +1,88 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempDir(t *testing.T) (dir string, cleanup func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target string, path string) {
+	t.Helper()
+	if err := os.Symlink(target, path); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+}
+
+// a Secret volume: the keys link to ..data/<key>, ..data links to the current timestamped directory
+func TestFindKubeConfigFilesSecretVolume(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(dir, "..2020_09_01_10_00_00.123", "prod.yaml"), "prod")
+	writeFile(t, filepath.Join(dir, "..2020_09_01_10_00_00.123", "test.yaml"), "test")
+	symlink(t, "..2020_09_01_10_00_00.123", filepath.Join(dir, "..data"))
+	symlink(t, filepath.Join("..data", "prod.yaml"), filepath.Join(dir, "prod.yaml"))
+	symlink(t, filepath.Join("..data", "test.yaml"), filepath.Join(dir, "test.yaml"))
+	symlink(t, filepath.Join("..data", "gone.yaml"), filepath.Join(dir, "gone.yaml"))
+	paths, err := FindKubeConfigFiles(dir, false, "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "prod.yaml"), filepath.Join(dir, "test.yaml")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("FindKubeConfigFiles = %v, want %v", paths, want)
+	}
+}
+
+func TestFindKubeConfigFiles(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(dir, "prod.yaml"), "prod")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "notes")
+	writeFile(t, filepath.Join(dir, ".hidden.yaml"), "hidden")
+	writeFile(t, filepath.Join(dir, "eu", "prod-eu.yaml"), "prod-eu")
+	symlink(t, "eu", filepath.Join(dir, "linked"))
+	paths, err := FindKubeConfigFiles(dir, false, "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "prod.yaml")}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("FindKubeConfigFiles = %v, want %v", paths, want)
+	}
+	paths, err = FindKubeConfigFiles(dir, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "eu", "prod-eu.yaml"), filepath.Join(dir, "notes.txt"), filepath.Join(dir, "prod.yaml")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("recursive FindKubeConfigFiles = %v, want %v", paths, want)
+	}
+	if _, err := FindKubeConfigFiles(dir, false, "["); err == nil {
+		t.Error("invalid pattern: err = nil")
+	}
+	if name := ClusterNameFromFileName(filepath.Join(dir, "eu", "prod-eu.yaml")); name != "prod-eu" {
+		t.Errorf("ClusterNameFromFileName = %s, want prod-eu", name)
+	}
+}
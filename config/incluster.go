@@ -0,0 +1,77 @@
+package config
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+const (
+	DefaultServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	serviceAccountTokenFile = "token"
+	serviceAccountCAFile = "ca.crt"
+	serviceHostEnv = "KUBERNETES_SERVICE_HOST"
+	servicePortEnv = "KUBERNETES_SERVICE_PORT"
+)
+
+// IsInCluster reports whether the process looks like it is running inside a pod,
+// i.e. the service env vars are set and the service account token is mounted
+func IsInCluster(serviceAccountDir string) bool {
+	if len(os.Getenv(serviceHostEnv)) == 0 || len(os.Getenv(servicePortEnv)) == 0 {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(serviceAccountDir, serviceAccountTokenFile))
+	return err == nil
+}
+
+// ReadInClusterConfig works like rest.InClusterConfig, but reads the token and CA bundle
+// from serviceAccountDir instead of the hardcoded mount path
+func ReadInClusterConfig(serviceAccountDir string) (kubeConfig *rest.Config, err error) {
+	host, port := os.Getenv(serviceHostEnv), os.Getenv(servicePortEnv)
+	if len(host) == 0 || len(port) == 0 {
+		err = k8serrors.InvalidKubeConfig(rest.ErrNotInCluster, serviceAccountDir)
+		return
+	}
+	tokenFile := filepath.Join(serviceAccountDir, serviceAccountTokenFile)
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		err = k8serrors.InvalidKubeConfig(err, serviceAccountDir)
+		return
+	}
+	tlsClientConfig := rest.TLSClientConfig{}
+	caFile := filepath.Join(serviceAccountDir, serviceAccountCAFile)
+	if _, err = certutil.NewPool(caFile); err != nil {
+		err = k8serrors.InvalidKubeConfig(err, serviceAccountDir)
+		return
+	}
+	tlsClientConfig.CAFile = caFile
+	kubeConfig = &rest.Config{
+		Host: "https://" + net.JoinHostPort(host, port),
+		TLSClientConfig: tlsClientConfig,
+		BearerToken: string(token),
+		BearerTokenFile: tokenFile,
+	}
+	return
+}
+
+func BuildKubernetesClientFromInClusterConfig(opts ...Option) (client *kubernetes.Clientset, err error) {
+	return BuildKubernetesClientFromServiceAccountDir(DefaultServiceAccountDir, opts...)
+}
+
+func BuildKubernetesClientFromServiceAccountDir(serviceAccountDir string, opts ...Option) (client *kubernetes.Clientset, err error) {
+	kubeConfig, err := ReadInClusterConfig(serviceAccountDir)
+	if err != nil {
+		return
+	}
+	client, err = BuildKubernetesClientFromKubeConfig(kubeConfig, opts...)
+	if err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"k8s.io/client-go/rest"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// setEnv sets the service env vars, empty unsets them, the returned func restores the old values
+func setEnv(t *testing.T, host string, port string) (restore func()) {
+	t.Helper()
+	var restores []func()
+	for key, value := range map[string]string{serviceHostEnv: host, servicePortEnv: port} {
+		old, set := os.LookupEnv(key)
+		key := key
+		restores = append(restores, func() {
+			if set {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+		if len(value) == 0 {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, value)
+		}
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
+
+// serviceAccountDir writes a token and a self-signed CA bundle like the kubelet mounts them
+func serviceAccountDir(t *testing.T) (dir string, cleanup func()) {
+	t.Helper()
+	dir, cleanup = tempDir(t)
+	certPEM, _, err := certutil.GenerateSelfSignedCertKey("kubernetes", nil, nil)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, serviceAccountTokenFile), "sa-token")
+	writeFile(t, filepath.Join(dir, serviceAccountCAFile), string(certPEM))
+	return
+}
+
+func TestReadInClusterConfig(t *testing.T) {
+	dir, cleanup := serviceAccountDir(t)
+	defer cleanup()
+	defer setEnv(t, "10.96.0.1", "443")()
+	if !IsInCluster(dir) {
+		t.Error("IsInCluster = false, want true")
+	}
+	kubeConfig, err := ReadInClusterConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeConfig.Host != "https://10.96.0.1:443" {
+		t.Errorf("Host = %s, want https://10.96.0.1:443", kubeConfig.Host)
+	}
+	if kubeConfig.BearerToken != "sa-token" || kubeConfig.BearerTokenFile != filepath.Join(dir, serviceAccountTokenFile) {
+		t.Errorf("token = %q from %s, want sa-token from the service account dir", kubeConfig.BearerToken, kubeConfig.BearerTokenFile)
+	}
+	if kubeConfig.TLSClientConfig.CAFile != filepath.Join(dir, serviceAccountCAFile) {
+		t.Errorf("CAFile = %s, want the service account ca.crt", kubeConfig.TLSClientConfig.CAFile)
+	}
+	if _, err := BuildKubernetesClientFromServiceAccountDir(dir); err != nil {
+		t.Errorf("BuildKubernetesClientFromServiceAccountDir: %v", err)
+	}
+}
+
+func TestReadInClusterConfigIPv6(t *testing.T) {
+	dir, cleanup := serviceAccountDir(t)
+	defer cleanup()
+	defer setEnv(t, "fd00::1", "443")()
+	kubeConfig, err := ReadInClusterConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeConfig.Host != "https://[fd00::1]:443" {
+		t.Errorf("Host = %s, want https://[fd00::1]:443", kubeConfig.Host)
+	}
+}
+
+func TestReadInClusterConfigNotInCluster(t *testing.T) {
+	dir, cleanup := serviceAccountDir(t)
+	defer cleanup()
+	defer setEnv(t, "", "")()
+	if IsInCluster(dir) {
+		t.Error("IsInCluster = true without the service env vars")
+	}
+	_, err := ReadInClusterConfig(dir)
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) || !errors.Is(err, rest.ErrNotInCluster) {
+		t.Errorf("err = %v, want ErrInvalidKubeConfig wrapping rest.ErrNotInCluster", err)
+	}
+}
+
+func TestReadInClusterConfigMissingToken(t *testing.T) {
+	dir, cleanup := serviceAccountDir(t)
+	defer cleanup()
+	defer setEnv(t, "10.96.0.1", "443")()
+	if err := os.Remove(filepath.Join(dir, serviceAccountTokenFile)); err != nil {
+		t.Fatal(err)
+	}
+	if IsInCluster(dir) {
+		t.Error("IsInCluster = true without a token")
+	}
+	_, err := ReadInClusterConfig(dir)
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want ErrInvalidKubeConfig for the missing token", err)
+	}
+}
+
+func TestReadInClusterConfigBadCA(t *testing.T) {
+	dir, cleanup := serviceAccountDir(t)
+	defer cleanup()
+	defer setEnv(t, "10.96.0.1", "443")()
+	writeFile(t, filepath.Join(dir, serviceAccountCAFile), "not a certificate")
+	_, err := ReadInClusterConfig(dir)
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("err = %v, want ErrInvalidKubeConfig for the bad CA bundle", err)
+	}
+	var e *k8serrors.Error
+	if !errors.As(err, &e) || e.Name != dir {
+		t.Errorf("err = %#v, want the service account dir as name", err)
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func ReadKubeConfigBytes(data []byte) (kubeConfig *rest.Config, err error) {
+	if len(data) == 0 {
+		err = k8serrors.InvalidKubeConfig(fmt.Errorf("empty kubeconfig"), "data")
+		return
+	}
+	kubeConfig, err = clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		err = k8serrors.InvalidKubeConfig(err, "data")
+		return
+	}
+	return
+}
+
+func DecodeKubeConfigBase64(encoded string) (data []byte, err error) {
+	data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		err = k8serrors.InvalidKubeConfig(fmt.Errorf("decode base64: %w", err), "base64")
+		return
+	}
+	return
+}
+
+// ReadKubeConfigEnvData returns the kubeconfig held by the environment variable envName,
+// the value may be raw YAML or base64 encoded
+func ReadKubeConfigEnvData(envName string) (data []byte, err error) {
+	value, ok := os.LookupEnv(envName)
+	if !ok || len(strings.TrimSpace(value)) == 0 {
+		err = k8serrors.InvalidKubeConfig(fmt.Errorf("environment variable is empty"), "env:"+envName)
+		return
+	}
+	// raw YAML never decodes as base64 because of ':' and whitespace
+	if decoded, decodeErr := DecodeKubeConfigBase64(value); decodeErr == nil {
+		return decoded, nil
+	}
+	return []byte(value), nil
+}
+
+func BuildKubernetesClientFromKubeConfigBytes(data []byte, opts ...Option) (client *kubernetes.Clientset, err error) {
+	kubeConfig, err := ReadKubeConfigBytes(data)
+	if err != nil {
+		return
+	}
+	client, err = BuildKubernetesClientFromKubeConfig(kubeConfig, opts...)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func BuildKubernetesClientFromKubeConfigBase64(encoded string, opts ...Option) (client *kubernetes.Clientset, err error) {
+	data, err := DecodeKubeConfigBase64(encoded)
+	if err != nil {
+		return
+	}
+	return BuildKubernetesClientFromKubeConfigBytes(data, opts...)
+}
+
+func BuildKubernetesClientFromKubeConfigEnv(envName string, opts ...Option) (client *kubernetes.Clientset, err error) {
+	data, err := ReadKubeConfigEnvData(envName)
+	if err != nil {
+		return
+	}
+	return BuildKubernetesClientFromKubeConfigBytes(data, opts...)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+)
+
+func TestReadKubeConfigBytes(t *testing.T) {
+	kubeConfig, err := ReadKubeConfigBytes([]byte(kubeConfig("prod", "https://prod:6443")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeConfig.Host != "https://prod:6443" || kubeConfig.BearerToken != "prod-token" {
+		t.Errorf("config = %s with token %q, want prod's server and token", kubeConfig.Host, kubeConfig.BearerToken)
+	}
+	for _, data := range []string{"", "clusters: ["} {
+		if _, err := ReadKubeConfigBytes([]byte(data)); !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+			t.Errorf("ReadKubeConfigBytes(%q): err = %v, want ErrInvalidKubeConfig", data, err)
+		}
+	}
+}
+
+func TestDecodeKubeConfigBase64(t *testing.T) {
+	raw := kubeConfig("prod", "https://prod:6443")
+	// a trailing newline is what `base64 < kubeconfig` leaves behind
+	data, err := DecodeKubeConfigBase64(base64.StdEncoding.EncodeToString([]byte(raw)) + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != raw {
+		t.Errorf("decoded = %q, want the kubeconfig", data)
+	}
+	if _, err := DecodeKubeConfigBase64("not base64!"); !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("bad base64: err = %v, want ErrInvalidKubeConfig", err)
+	}
+}
+
+func TestReadKubeConfigEnvData(t *testing.T) {
+	const envName = "TEST_KUBECONFIG_DATA"
+	old, set := os.LookupEnv(envName)
+	defer func() {
+		if set {
+			os.Setenv(envName, old)
+		} else {
+			os.Unsetenv(envName)
+		}
+	}()
+	raw := kubeConfig("prod", "https://prod:6443")
+	for name, value := range map[string]string{
+		"raw": raw,
+		"base64": base64.StdEncoding.EncodeToString([]byte(raw)),
+	} {
+		os.Setenv(envName, value)
+		data, err := ReadKubeConfigEnvData(envName)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(data) != raw {
+			t.Errorf("%s: data = %q, want the kubeconfig", name, data)
+		}
+	}
+	os.Setenv(envName, " ")
+	if _, err := ReadKubeConfigEnvData(envName); !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("blank variable: err = %v, want ErrInvalidKubeConfig", err)
+	}
+	os.Unsetenv(envName)
+	if _, err := BuildKubernetesClientFromKubeConfigEnv(envName); !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("unset variable: err = %v, want ErrInvalidKubeConfig", err)
+	}
+}
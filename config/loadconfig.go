@@ -1,6 +1,7 @@
 package config
 
 import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
 	"os"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -8,30 +9,37 @@ import (
 	"fmt"
 )
 
-func readKubeConfigFile(kubeConfigPath string) (kubeConfig *rest.Config, err error) {
+func ReadKubeConfigFile(kubeConfigPath string) (kubeConfig *rest.Config, err error) {
 	kubeConfig, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath)
 	if err != nil {
-		err = fmt.Errorf("Read kubeconfig Error:\n %s", err)
+		err = k8serrors.InvalidKubeConfig(err, kubeConfigPath)
 		return
 	}
 	return
 }
 
-func BuildKubernetesClientFromKubeConfig(kubeConfig *rest.Config) (client *kubernetes.Clientset, err error) {
+// BuildKubernetesClientFromKubeConfig fails with ErrInvalidKubeConfig named after the API server host
+func BuildKubernetesClientFromKubeConfig(kubeConfig *rest.Config, opts ...Option) (client *kubernetes.Clientset, err error) {
+	host := kubeConfig.Host
+	kubeConfig, err = ApplyOptions(kubeConfig, opts...)
+	if err != nil {
+		err = k8serrors.InvalidKubeConfig(fmt.Errorf("build kubernetes client: %w", err), host)
+		return
+	}
 	client, err = kubernetes.NewForConfig(kubeConfig)
 	if err != nil {
-		err = fmt.Errorf("build kubernetes client error:\n %s", err)
+		err = k8serrors.InvalidKubeConfig(fmt.Errorf("build kubernetes client: %w", err), host)
 		return
 	}
 	return
 }
 
-func BuildKubernetesClientFromKubeConfigFile(kubeConfigPath string) (client *kubernetes.Clientset, err error) {
-	kubeConfig, err := readKubeConfigFile(kubeConfigPath)
+func BuildKubernetesClientFromKubeConfigFile(kubeConfigPath string, opts ...Option) (client *kubernetes.Clientset, err error) {
+	kubeConfig, err := ReadKubeConfigFile(kubeConfigPath)
 	if err != nil {
 		return
 	}
-	client, err = BuildKubernetesClientFromKubeConfig(kubeConfig)
+	client, err = BuildKubernetesClientFromKubeConfig(kubeConfig, opts...)
 	if err != nil {
 		return
 	}
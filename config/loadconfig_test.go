@@ -0,0 +1,30 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"k8s.io/client-go/rest"
+)
+
+func TestBuildKubernetesClientInvalidKubeConfig(t *testing.T) {
+	optionErr := errors.New("option failed")
+	failing := func(kubeConfig *rest.Config) error {
+		return optionErr
+	}
+	_, err := BuildKubernetesClientFromKubeConfig(&rest.Config{Host: "https://10.0.0.1:6443"}, failing)
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) || !errors.Is(err, optionErr) {
+		t.Errorf("option error: err = %v, want ErrInvalidKubeConfig wrapping the option error", err)
+	}
+	_, err = BuildKubernetesClientFromKubeConfig(&rest.Config{Host: "https://10.0.0.1:6443", QPS: 5})
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("qps without burst: err = %v, want ErrInvalidKubeConfig", err)
+	}
+	var e *k8serrors.Error
+	if !errors.As(err, &e) || e.Name != "https://10.0.0.1:6443" {
+		t.Errorf("error = %#v, want the host as name", e)
+	}
+	if _, err := ReadKubeConfigFile("testdata/missing"); !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("missing file: err = %v, want ErrInvalidKubeConfig", err)
+	}
+}
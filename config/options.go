@@ -0,0 +1,79 @@
+package config
+
+import (
+	"net/http"
+	"time"
+	"k8s.io/client-go/rest"
+)
+
+// Option tunes the rest config before the clientset is built
+type Option func(kubeConfig *rest.Config) error
+
+func WithQPS(qps float32) Option {
+	return func(kubeConfig *rest.Config) error {
+		kubeConfig.QPS = qps
+		return nil
+	}
+}
+
+func WithBurst(burst int) Option {
+	return func(kubeConfig *rest.Config) error {
+		kubeConfig.Burst = burst
+		return nil
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(kubeConfig *rest.Config) error {
+		kubeConfig.Timeout = timeout
+		return nil
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(kubeConfig *rest.Config) error {
+		kubeConfig.UserAgent = userAgent
+		return nil
+	}
+}
+
+func WithInsecureSkipVerify() Option {
+	return func(kubeConfig *rest.Config) error {
+		// client-go refuses a root CA together with the insecure flag
+		kubeConfig.Insecure = true
+		kubeConfig.CAData = nil
+		kubeConfig.CAFile = ""
+		return nil
+	}
+}
+
+func WithCAData(caData []byte) Option {
+	return func(kubeConfig *rest.Config) error {
+		kubeConfig.Insecure = false
+		kubeConfig.CAData = caData
+		kubeConfig.CAFile = ""
+		return nil
+	}
+}
+
+// ApplyOptions returns a copy of kubeConfig with opts applied, kubeConfig itself is left untouched
+func ApplyOptions(kubeConfig *rest.Config, opts ...Option) (ret *rest.Config, err error) {
+	if len(opts) == 0 {
+		return kubeConfig, nil
+	}
+	ret = rest.CopyConfig(kubeConfig)
+	for _, opt := range opts {
+		if err = opt(ret); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// WithWrapTransport adds a round tripper middleware, e.g. for logging or metrics
+func WithWrapTransport(fn func(rt http.RoundTripper) http.RoundTripper) Option {
+	return func(kubeConfig *rest.Config) error {
+		kubeConfig.Wrap(fn)
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"k8s.io/client-go/rest"
+)
+
+func TestApplyOptions(t *testing.T) {
+	kubeConfig := &rest.Config{
+		Host: "https://10.0.0.1:6443",
+		TLSClientConfig: rest.TLSClientConfig{CAFile: "/etc/kubernetes/ca.crt"},
+	}
+	if ret, err := ApplyOptions(kubeConfig); err != nil || ret != kubeConfig {
+		t.Errorf("no options: ApplyOptions = %p, %v, want the config itself", ret, err)
+	}
+	ret, err := ApplyOptions(kubeConfig,
+		WithQPS(50),
+		WithBurst(100),
+		WithTimeout(time.Minute),
+		WithUserAgent("deployer/1.0"),
+		WithInsecureSkipVerify(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.QPS != 50 || ret.Burst != 100 || ret.Timeout != time.Minute || ret.UserAgent != "deployer/1.0" {
+		t.Errorf("config = %+v, want qps, burst, timeout and user agent set", ret)
+	}
+	if !ret.Insecure || len(ret.CAFile) != 0 {
+		t.Errorf("tls = %+v, want insecure without a root CA", ret.TLSClientConfig)
+	}
+	if kubeConfig.QPS != 0 || kubeConfig.Insecure || kubeConfig.CAFile != "/etc/kubernetes/ca.crt" {
+		t.Errorf("original config = %+v, want it untouched", kubeConfig)
+	}
+
+	// the later option wins
+	ret, err = ApplyOptions(kubeConfig, WithInsecureSkipVerify(), WithCAData([]byte("ca")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Insecure || string(ret.CAData) != "ca" || len(ret.CAFile) != 0 {
+		t.Errorf("tls = %+v, want the CA data verified", ret.TLSClientConfig)
+	}
+
+	optionErr := errors.New("option failed")
+	ret, err = ApplyOptions(kubeConfig, func(kubeConfig *rest.Config) error {
+		return optionErr
+	})
+	if ret != nil || err != optionErr {
+		t.Errorf("failing option: ApplyOptions = %v, %v, want the option error", ret, err)
+	}
+}
+
+type recordingRoundTripper struct {
+	name 					string
+	calls 					*[]string
+	delegate 				http.RoundTripper
+}
+
+func (rt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	*rt.calls = append(*rt.calls, rt.name)
+	return rt.delegate.RoundTrip(req)
+}
+
+func TestWithWrapTransport(t *testing.T) {
+	var calls []string
+	wrap := func(name string) Option {
+		return WithWrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &recordingRoundTripper{name: name, calls: &calls, delegate: rt}
+		})
+	}
+	ret, err := ApplyOptions(&rest.Config{Host: "https://10.0.0.1:6443"}, wrap("logging"), wrap("metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "base")
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://10.0.0.1:6443/api", nil)
+	if _, err := ret.WrapTransport(base).RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	// every wrapper sees the request, the one added last is outermost
+	if len(calls) != 3 || calls[0] != "metrics" || calls[1] != "logging" || calls[2] != "base" {
+		t.Errorf("calls = %v, want [metrics logging base]", calls)
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
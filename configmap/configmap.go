@@ -1,15 +1,30 @@
 package configmap
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
 	"sigs.k8s.io/yaml"
 	"encoding/json"
 )
 
+const resourceType = "configMap"
+
 type configMap struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
 	err 					error
 }
 
@@ -18,10 +33,23 @@ type ConfigMap interface {
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *configMap {
+func NewForClient(client kubernetes.Interface) *configMap {
 	return &configMap{
 		client: client,
 	}
@@ -31,70 +59,258 @@ func (c *configMap) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *configMap) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors and update conflicts, nil sends every request once
+func (c *configMap) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+// SetApplyOptions sets the field manager and force flag of Apply, nil uses the defaults
+func (c *configMap) SetApplyOptions(options *apply.Options) {
+	c.applyOptions = options
+}
+
+func (c *configMap) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *configMap) Create(input string) (err error) {
+	return c.CreateContext(context.Background(), input)
+}
+
+func (c *configMap) CreateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	configMap := new(corev1.ConfigMap)
 	err = yaml.Unmarshal([]byte(input), configMap)
 	if err != nil {
-		return
-	}
-	// output for debug
-	_, err = yaml.Marshal(configMap)
-	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := configMap.Namespace
-	_, err = c.client.CoreV1().ConfigMaps(namespace).Create(configMap)
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.client.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, configMap.Name)
 	}
 	return
 }
 
 func (c *configMap) Delete(namespace string, name string) (err error) {
+	return c.DeleteContext(context.Background(), namespace, name)
+}
+
+func (c *configMap) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	err = c.client.CoreV1().ConfigMaps(namespace).Delete(name, &metav1.DeleteOptions{})
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.client.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, name)
 	}
 	return
 }
 
 func (c *configMap) Update(input string) (err error) {
+	return c.UpdateContext(context.Background(), input)
+}
+
+func (c *configMap) UpdateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	configMap := new(corev1.ConfigMap)
 	err = yaml.Unmarshal([]byte(input), configMap)
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
-	// output for debug
-	_, err = yaml.Marshal(configMap)
+	namespace := configMap.Namespace
+	conflict := false
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		if conflict {
+			// reapply our object on top of the live resourceVersion
+			current, err := c.client.CoreV1().ConfigMaps(namespace).Get(ctx, configMap.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			configMap.ResourceVersion = current.ResourceVersion
+		}
+		_, err := c.client.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		conflict = retry.IsConflict(err)
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, configMap.Name)
+	}
+	return
+}
+
+func (c *configMap) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates the configmap or brings it in line with input, fields input leaves out are kept
+func (c *configMap) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	configMap := new(corev1.ConfigMap)
+	err = yaml.Unmarshal([]byte(input), configMap)
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	manifest, err := apply.Manifest(input, corev1.SchemeGroupVersion.String(), "ConfigMap")
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := configMap.Namespace
-	_, err = c.client.CoreV1().ConfigMaps(namespace).Update(configMap)
+	configMaps := c.client.CoreV1().ConfigMaps(namespace)
+	_, err = apply.Do(ctx, c.applyOptions, c.retryPolicy, apply.Target{
+		Manifest: manifest,
+		Schema: &corev1.ConfigMap{},
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			_, err := configMaps.Patch(ctx, configMap.Name, patchType, data, options)
+			return err
+		},
+		Get: func(ctx context.Context) ([]byte, error) {
+			current, err := configMaps.Get(ctx, configMap.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(current)
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			object := new(corev1.ConfigMap)
+			if err := json.Unmarshal(manifest, object); err != nil {
+				return err
+			}
+			_, err := configMaps.Create(ctx, object, metav1.CreateOptions{})
+			return err
+		},
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, configMap.Name)
 	}
 	return
 }
 
 func (c *configMap) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *configMap) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	configMap, err := c.client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	var configMap *corev1.ConfigMap
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		configMap, err = c.client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
 	d, err := json.Marshal(configMap)
 	if err != nil {
 		return "", err
 	}
 	ret = string(d)
 	return
-}
\ No newline at end of file
+}
+
+func (c *configMap) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the configmap with a YAML or JSON body and returns the patched object
+func (c *configMap) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var configMap *corev1.ConfigMap
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		configMap, err = c.client.CoreV1().ConfigMaps(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(configMap)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *configMap) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of configMaps, options.Limit and options.Continue page through the rest
+func (c *configMap) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var configMaps *corev1.ConfigMapList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		configMaps, err = c.client.CoreV1().ConfigMaps(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&configMaps.ListMeta)
+	for i := range configMaps.Items {
+		d, err := json.Marshal(&configMaps.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *configMap) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of configMaps lazily, one request per options.Limit items
+func (c *configMap) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to configMaps until ctx is done, reconnecting and resuming on its own
+func (c *configMap) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CoreV1().ConfigMaps(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.CoreV1().ConfigMaps(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
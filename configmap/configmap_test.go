@@ -0,0 +1,137 @@
+package configmap
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const configMapYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+data:
+  level: info
+`
+
+var testPolicy = retry.Policy{
+	MaxAttempts: 3,
+	InitialBackoff: time.Millisecond,
+	Multiplier: 1,
+}
+
+func newConfigMap(name string, labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "default",
+			Labels: labels,
+		},
+	}
+}
+
+func TestConfigMapCreateGetUpdateDelete(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	if err := c.Create(configMapYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(configMapYAML); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	if err := c.Update("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: default\ndata:\n  level: debug\n"); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := c.Get("default", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMap := new(corev1.ConfigMap)
+	json.Unmarshal([]byte(ret), configMap)
+	if configMap.Data["level"] != "debug" {
+		t.Errorf("level after Update = %q, want debug", configMap.Data["level"])
+	}
+	if err := c.Delete("default", "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("default", "settings"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConfigMapUpdateRetriesConflicts(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap("settings", nil))
+	conflicts := 0
+	client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			conflicts++
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "settings", errors.New("modified"))
+		}
+		return false, nil, nil
+	})
+	c := NewForClient(client)
+	c.SetRetryPolicy(&testPolicy)
+	if err := c.Update(configMapYAML); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts != 1 {
+		t.Errorf("conflicts = %d, want 1", conflicts)
+	}
+}
+
+func TestConfigMapCreateIsNotRetriedOnServerErrors(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	attempts := 0
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		return true, nil, apierrors.NewInternalError(errors.New("etcd timeout"))
+	})
+	c := NewForClient(client)
+	c.SetRetryPolicy(&testPolicy)
+	if err := c.Create(configMapYAML); err == nil {
+		t.Fatal("Create succeeded, want the internal error")
+	}
+	// the server may have stored the object before failing, creating it again is not safe
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestConfigMapPatchAndList(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(
+		newConfigMap("settings", map[string]string{"app": "nginx"}),
+		newConfigMap("other", nil),
+	))
+	ret, err := c.Patch("default", "settings", types.StrategicMergePatchType, "data:\n  level: warn\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMap := new(corev1.ConfigMap)
+	json.Unmarshal([]byte(ret), configMap)
+	if configMap.Data["level"] != "warn" {
+		t.Errorf("level after Patch = %q, want warn", configMap.Data["level"])
+	}
+	if _, err := c.Patch("default", "settings", types.JSONPatchType, "data: {}"); !errors.Is(err, k8serrors.ErrInvalidManifest) {
+		t.Errorf("Patch with an object as JSON patch: err = %v, want ErrInvalidManifest", err)
+	}
+	page, err := c.List("default", list.Options{LabelSelector: "app=nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("List(app=nginx) returned %d items, want 1", len(page.Items))
+	}
+}
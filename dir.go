@@ -0,0 +1,270 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	k8sconfig "github.com/zhanghaohao/kubernetes-client/config"
+)
+
+// KubeConfigDir is a directory holding one kubeconfig file per cluster
+type KubeConfigDir struct {
+	Dir 					string
+	Recursive 				bool
+	// Pattern filters file names with filepath.Match syntax, e.g. "*.yaml", empty matches every file
+	Pattern 				string
+	// NameFromContext names each cluster after the file's current-context instead of the file name
+	NameFromContext 		bool
+}
+
+type dirWatcher struct {
+	clients 				*k8sClients
+	dir 					KubeConfigDir
+	interval 				time.Duration
+	onReload 				ReloadFunc
+	// files is keyed by path, clusterName is empty while the file has never been loaded
+	files 					map[string]*watchedFile
+	stopCh 					chan struct{}
+	stopOnce 				sync.Once
+}
+
+type watchedFile struct {
+	clusterName 			string
+	hash 					[]byte
+}
+
+func (d KubeConfigDir) clusterName(path string) (clusterName string, err error) {
+	if d.NameFromContext {
+		return k8sconfig.CurrentContextName(path)
+	}
+	return k8sconfig.ClusterNameFromFileName(path), nil
+}
+
+func (d KubeConfigDir) metadata(path string, clusterName string) ClusterMetadata {
+	metadata := ClusterMetadata{
+		Source: path,
+	}
+	if d.NameFromContext {
+		metadata.Context = clusterName
+	}
+	return metadata
+}
+
+// kubeConfigPaths scans the directory, two files resolving to the same cluster name are an error
+func (d KubeConfigDir) kubeConfigPaths() (paths []KubeConfigPath, err error) {
+	files, err := k8sconfig.FindKubeConfigFiles(d.Dir, d.Recursive, d.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]string)
+	for _, file := range files {
+		name, err := d.clusterName(file)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("cluster name %s is used by both %s and %s", name, other, file)
+		}
+		seen[name] = file
+		paths = append(paths, KubeConfigPath{
+			ClusterName: name,
+			Path: file,
+		})
+	}
+	return
+}
+
+func NewFromKubeconfigDir(dir KubeConfigDir, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build one k8s client per kubeconfig file found in the directory
+	 */
+	paths, err := dir.kubeConfigPaths()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		err := fmt.Errorf("no kubeconfig file found in %s", dir.Dir)
+		return nil, err
+	}
+	c := newK8sClients(opts...)
+	for _, path := range paths {
+		kubeConfig, err := k8sconfig.ReadKubeConfigFile(path.Path)
+		if err != nil {
+			return nil, err
+		}
+		err = c.loadConfig(path.ClusterName, kubeConfig, dir.metadata(path.Path, path.ClusterName))
+		if err != nil {
+			return nil, err
+		}
+	}
+	clients = c
+	return
+}
+
+// WatchKubeconfigDir polls the directory: new files register clusters, changed files rebuild them
+// and removed files unregister them. Only clusters loaded from the directory are ever removed.
+// The returned stop func (or Close) ends the watch.
+func (k *k8sClients) WatchKubeconfigDir(dir KubeConfigDir, interval time.Duration, onReload ReloadFunc) (stop func()) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	w := &dirWatcher{
+		clients: k,
+		dir: dir,
+		interval: interval,
+		onReload: onReload,
+		files: make(map[string]*watchedFile),
+		stopCh: make(chan struct{}),
+	}
+	// files already registered, e.g. by NewFromKubeconfigDir, only reload once they change
+	if paths, err := dir.kubeConfigPaths(); err == nil {
+		for _, path := range paths {
+			if !k.Has(path.ClusterName) {
+				continue
+			}
+			hash, err := hashFile(path.Path)
+			if err != nil {
+				continue
+			}
+			w.files[path.Path] = &watchedFile{
+				clusterName: path.ClusterName,
+				hash: hash,
+			}
+		}
+	}
+	go w.run()
+	stop = func() {
+		w.stopOnce.Do(func() {
+			close(w.stopCh)
+		})
+	}
+	k.addStop(stop)
+	return
+}
+
+func (w *dirWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	w.check()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *dirWatcher) check() {
+	files, err := k8sconfig.FindKubeConfigFiles(w.dir.Dir, w.dir.Recursive, w.dir.Pattern)
+	if err != nil {
+		// the directory may be remounted, try again on the next tick
+		return
+	}
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+	/*
+	handle removals first, so a file renamed while keeping its cluster name
+	is not reported as a duplicate of itself
+	 */
+	var removed []string
+	for path := range w.files {
+		if !present[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		f := w.files[path]
+		delete(w.files, path)
+		if len(f.clusterName) != 0 {
+			w.remove(f.clusterName, path)
+		}
+	}
+	for _, file := range files {
+		w.checkFile(file)
+	}
+}
+
+func (w *dirWatcher) checkFile(path string) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return
+	}
+	f, known := w.files[path]
+	if known && bytes.Equal(hash, f.hash) {
+		return
+	}
+	if !known {
+		f = &watchedFile{}
+		w.files[path] = f
+	}
+	// report each change once, a broken file is retried when it changes again
+	f.hash = hash
+	name, err := w.dir.clusterName(path)
+	event := ReloadEvent{
+		ClusterName: name,
+		Path: path,
+		Op: ReloadAdded,
+	}
+	if err != nil {
+		event.ClusterName = f.clusterName
+		w.emit(event, err)
+		return
+	}
+	for other, o := range w.files {
+		if other != path && o.clusterName == name {
+			w.emit(event, fmt.Errorf("cluster name %s is already used by %s", name, other))
+			return
+		}
+	}
+	metadata := w.dir.metadata(path, name)
+	if len(f.clusterName) != 0 {
+		if f.clusterName == name {
+			event.Op = ReloadUpdated
+			// keep labels and annotations set at runtime
+			if current, ok := w.clients.Metadata(name); ok {
+				current.Source = metadata.Source
+				current.Context = metadata.Context
+				current.LoadedAt = time.Now()
+				metadata = current
+			}
+		} else {
+			// the file now names another context, drop the old cluster
+			w.remove(f.clusterName, path)
+			f.clusterName = ""
+		}
+	}
+	kubeConfig, err := k8sconfig.ReadKubeConfigFile(path)
+	if err == nil {
+		err = w.clients.loadConfig(name, kubeConfig, metadata)
+	}
+	if err == nil {
+		f.clusterName = name
+	}
+	w.emit(event, err)
+}
+
+func (w *dirWatcher) remove(clusterName string, path string) {
+	// the name may have been taken over by a cluster loaded some other way
+	if metadata, ok := w.clients.Metadata(clusterName); ok && metadata.Source == path {
+		w.clients.Remove(clusterName)
+	}
+	w.emit(ReloadEvent{
+		ClusterName: clusterName,
+		Path: path,
+		Op: ReloadRemoved,
+	}, nil)
+}
+
+func (w *dirWatcher) emit(event ReloadEvent, err error) {
+	event.Err = err
+	if w.onReload != nil {
+		w.onReload(event)
+	}
+}
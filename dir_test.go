@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatchKubeconfigDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	prod := filepath.Join(dir, "prod.yaml")
+	writeFileAtomic(t, prod, fmt.Sprintf(testKubeConfig, "prod", "https://prod:6443"))
+	kubeConfigDir := KubeConfigDir{
+		Dir: dir,
+		Pattern: "*.yaml",
+	}
+	clients, err := NewFromKubeconfigDir(kubeConfigDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Close()
+	events := make(chan ReloadEvent, 10)
+	clients.WatchKubeconfigDir(kubeConfigDir, 10*time.Millisecond, func(event ReloadEvent) {
+		events <- event
+	})
+
+	test := filepath.Join(dir, "test.yaml")
+	writeFileAtomic(t, test, fmt.Sprintf(testKubeConfig, "test", "https://test:6443"))
+	event := nextReloadEvent(t, events)
+	if event.Op != ReloadAdded || event.ClusterName != "test" || event.Path != test || event.Err != nil {
+		t.Errorf("event after create = %+v, want test added", event)
+	}
+
+	writeFileAtomic(t, prod, fmt.Sprintf(testKubeConfig, "prod", "https://prod-2:6443"))
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadUpdated || event.ClusterName != "prod" || event.Err != nil {
+		t.Errorf("event after rewrite = %+v, want prod updated", event)
+	}
+
+	if err := os.Remove(test); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadRemoved || event.ClusterName != "test" {
+		t.Errorf("event after remove = %+v, want test removed", event)
+	}
+	if names := clients.List(); !reflect.DeepEqual(names, []string{"prod"}) {
+		t.Errorf("clusters = %v, want [prod]", names)
+	}
+
+	// a cluster loaded some other way under the same name must survive the file going away
+	clients.LoadWithMetadata("prod", fake.NewSimpleClientset(), ClusterMetadata{Source: "portal"})
+	if err := os.Remove(prod); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadRemoved || event.ClusterName != "prod" || !clients.Has("prod") {
+		t.Errorf("event after remove = %+v, want prod kept as it was replaced by another source", event)
+	}
+}
@@ -0,0 +1,462 @@
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// resource works on any kind the API server knows, including CRDs, through the dynamic client.
+// The kind is resolved to a resource through the REST mapper on every call, so CRDs installed
+// after the client was built are found too.
+type resource struct {
+	client 					dynamic.Interface
+	mapper 					meta.RESTMapper
+	gvk 					schema.GroupVersionKind
+	// kind is the raw kind string, e.g. Rollout, Rollout.argoproj.io or Certificate.v1.cert-manager.io
+	kind 					string
+	clusterName 			string
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
+	err 					error
+}
+
+type Resource interface {
+	SetErr(err error)
+	Create(input string) (err error)
+	Delete(namespace string, name string) (err error)
+	Update(input string) (err error)
+	Apply(input string) (err error)
+	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
+}
+
+// NewForGVK addresses the kind by group, version and kind, an empty version picks the preferred one
+func NewForGVK(client dynamic.Interface, mapper meta.RESTMapper, gvk schema.GroupVersionKind) *resource {
+	return &resource{
+		client: client,
+		mapper: mapper,
+		gvk: gvk,
+	}
+}
+
+// NewForKind addresses the kind the way kubectl does: Kind, Kind.group or Kind.version.group
+func NewForKind(client dynamic.Interface, mapper meta.RESTMapper, kind string) *resource {
+	return &resource{
+		client: client,
+		mapper: mapper,
+		kind: kind,
+	}
+}
+
+func (c *resource) SetErr(err error)  {
+	c.err = err
+}
+
+func (c *resource) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors and update conflicts, nil sends every request once
+func (c *resource) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+// SetApplyOptions sets the field manager and force flag of Apply, nil uses the defaults
+func (c *resource) SetApplyOptions(options *apply.Options) {
+	c.applyOptions = options
+}
+
+func (c *resource) resourceType() string {
+	if len(c.kind) != 0 {
+		return c.kind
+	}
+	return c.gvk.String()
+}
+
+func (c *resource) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, c.resourceType(), namespace, name)
+}
+
+// mapping resolves the kind, the mapper's discovery cache is refreshed once when the kind is unknown
+func (c *resource) mapping() (mapping *meta.RESTMapping, err error) {
+	if c.client == nil || c.mapper == nil {
+		return nil, fmt.Errorf("cluster %s has no dynamic client", c.clusterName)
+	}
+	mapping, err = c.resolve()
+	if meta.IsNoMatchError(err) {
+		if r, ok := c.mapper.(interface{ Reset() }); ok {
+			r.Reset()
+			mapping, err = c.resolve()
+		}
+	}
+	if err != nil {
+		return nil, &k8serrors.Error{
+			Kind: k8serrors.ErrUnknownResourceType,
+			Cluster: c.clusterName,
+			ResourceType: c.resourceType(),
+			Err: err,
+		}
+	}
+	return
+}
+
+func (c *resource) resolve() (mapping *meta.RESTMapping, err error) {
+	if len(c.kind) == 0 {
+		if len(c.gvk.Version) == 0 {
+			return c.mapper.RESTMapping(c.gvk.GroupKind())
+		}
+		return c.mapper.RESTMapping(c.gvk.GroupKind(), c.gvk.Version)
+	}
+	gvk, gk := schema.ParseKindArg(c.kind)
+	if gvk != nil {
+		// Kind.a.b is either version a of group b or group a.b, try the version first like kubectl
+		if mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			return
+		}
+	}
+	if len(gk.Group) == 0 {
+		// a bare kind may live in any group, look it up by its singular resource name
+		found, err := c.mapper.KindFor(schema.GroupVersionResource{Resource: strings.ToLower(gk.Kind)})
+		if err != nil {
+			return nil, err
+		}
+		return c.mapper.RESTMapping(found.GroupKind(), found.Version)
+	}
+	return c.mapper.RESTMapping(gk)
+}
+
+func (c *resource) resourceClient(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.client.Resource(mapping.Resource)
+	}
+	return c.client.Resource(mapping.Resource).Namespace(namespace)
+}
+
+// decode reads YAML or JSON input, apiVersion and kind are filled in from mapping when missing
+func (c *resource) decode(input string, mapping *meta.RESTMapping) (object *unstructured.Unstructured, err error) {
+	d, err := yaml.YAMLToJSON([]byte(input))
+	if err != nil {
+		return nil, k8serrors.InvalidManifest(err, c.clusterName, c.resourceType())
+	}
+	// unstructured.UnmarshalJSON insists on a kind, decode by hand so manifests may leave it out
+	content := make(map[string]interface{})
+	if err = utiljson.Unmarshal(d, &content); err != nil {
+		return nil, k8serrors.InvalidManifest(err, c.clusterName, c.resourceType())
+	}
+	if len(content) == 0 {
+		return nil, k8serrors.InvalidManifest(fmt.Errorf("empty manifest"), c.clusterName, c.resourceType())
+	}
+	object = &unstructured.Unstructured{
+		Object: content,
+	}
+	gvk := object.GroupVersionKind()
+	if len(gvk.Kind) == 0 {
+		object.SetGroupVersionKind(mapping.GroupVersionKind)
+	} else if gvk.GroupKind() != mapping.GroupVersionKind.GroupKind() {
+		err = fmt.Errorf("manifest is a %s, expected %s", gvk.GroupKind(), mapping.GroupVersionKind.GroupKind())
+		return nil, k8serrors.InvalidManifest(err, c.clusterName, c.resourceType())
+	}
+	// keep the body in line with the URL resourceClient builds
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		object.SetNamespace("")
+	}
+	return
+}
+
+func (c *resource) Create(input string) (err error) {
+	return c.CreateContext(context.Background(), input)
+}
+
+func (c *resource) CreateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	object, err := c.decode(input, mapping)
+	if err != nil {
+		return
+	}
+	namespace := object.GetNamespace()
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.resourceClient(mapping, namespace).Create(ctx, object, metav1.CreateOptions{})
+		return err
+	})
+	if err != nil {
+		return c.wrapErr(err, namespace, object.GetName())
+	}
+	return
+}
+
+func (c *resource) Delete(namespace string, name string) (err error) {
+	return c.DeleteContext(context.Background(), namespace, name)
+}
+
+func (c *resource) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.resourceClient(mapping, namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	})
+	if err != nil {
+		return c.wrapErr(err, namespace, name)
+	}
+	return
+}
+
+func (c *resource) Update(input string) (err error) {
+	return c.UpdateContext(context.Background(), input)
+}
+
+func (c *resource) UpdateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	object, err := c.decode(input, mapping)
+	if err != nil {
+		return
+	}
+	namespace := object.GetNamespace()
+	conflict := false
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		if conflict {
+			// reapply our object on top of the live resourceVersion
+			current, err := c.resourceClient(mapping, namespace).Get(ctx, object.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			object.SetResourceVersion(current.GetResourceVersion())
+		}
+		_, err := c.resourceClient(mapping, namespace).Update(ctx, object, metav1.UpdateOptions{})
+		conflict = retry.IsConflict(err)
+		return err
+	})
+	if err != nil {
+		return c.wrapErr(err, namespace, object.GetName())
+	}
+	return
+}
+
+func (c *resource) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates the object or brings it in line with input, fields input leaves out are kept.
+// Without server-side apply the fallback merges with JSON merge patches, lists are replaced whole.
+func (c *resource) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	object, err := c.decode(input, mapping)
+	if err != nil {
+		return
+	}
+	manifest, err := object.MarshalJSON()
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, c.resourceType())
+	}
+	namespace := object.GetNamespace()
+	resourceClient := c.resourceClient(mapping, namespace)
+	_, err = apply.Do(ctx, c.applyOptions, c.retryPolicy, apply.Target{
+		Manifest: manifest,
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			_, err := resourceClient.Patch(ctx, object.GetName(), patchType, data, options)
+			return err
+		},
+		Get: func(ctx context.Context) ([]byte, error) {
+			current, err := resourceClient.Get(ctx, object.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return current.MarshalJSON()
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			created := new(unstructured.Unstructured)
+			if err := created.UnmarshalJSON(manifest); err != nil {
+				return err
+			}
+			_, err := resourceClient.Create(ctx, created, metav1.CreateOptions{})
+			return err
+		},
+	})
+	if err != nil {
+		return c.wrapErr(err, namespace, object.GetName())
+	}
+	return
+}
+
+func (c *resource) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *resource) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	var object *unstructured.Unstructured
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		object, err = c.resourceClient(mapping, namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := object.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *resource) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the object with a YAML or JSON body and returns the patched object.
+// Custom resources do not support strategic merge patches, use patch.Merge or patch.JSON for them.
+func (c *resource) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, c.resourceType())
+	}
+	var object *unstructured.Unstructured
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		object, err = c.resourceClient(mapping, namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := object.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *resource) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of objects, options.Limit and options.Continue page through the rest
+func (c *resource) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	namespace = options.Namespace(namespace)
+	resourceClient := c.resourceClient(mapping, namespace)
+	var objects *unstructured.UnstructuredList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		objects, err = resourceClient.List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(objects)
+	for i := range objects.Items {
+		d, err := objects.Items[i].MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *resource) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of objects lazily, one request per options.Limit items
+func (c *resource) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to the objects until ctx is done, reconnecting and resuming on its own
+func (c *resource) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	mapping, err := c.mapping()
+	if err != nil {
+		return
+	}
+	namespace = options.Namespace(namespace)
+	resourceClient := c.resourceClient(mapping, namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: c.resourceType(),
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return resourceClient.List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return resourceClient.Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
@@ -0,0 +1,196 @@
+package dynamic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const rolloutYAML = `
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: web
+  namespace: default
+spec:
+  replicas: 2
+`
+
+var (
+	rolloutGVK = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+	clusterIssuerGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "ClusterIssuer"}
+)
+
+func newMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{rolloutGVK.GroupVersion(), clusterIssuerGVK.GroupVersion()})
+	mapper.Add(rolloutGVK, meta.RESTScopeNamespace)
+	mapper.Add(clusterIssuerGVK, meta.RESTScopeRoot)
+	return mapper
+}
+
+func TestResourceCreateGetUpdateDelete(t *testing.T) {
+	c := NewForKind(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), newMapper(), "Rollout.argoproj.io")
+	if err := c.Create(rolloutYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(rolloutYAML); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	ret, err := c.Get("default", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := new(unstructured.Unstructured)
+	if err := utiljson.Unmarshal([]byte(ret), &object.Object); err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(object.Object, "spec", "replicas"); replicas != 2 {
+		t.Errorf("replicas = %d, want 2", replicas)
+	}
+	if err := c.Update("apiVersion: argoproj.io/v1alpha1\nkind: Rollout\nmetadata:\n  name: web\n  namespace: default\nspec:\n  replicas: 5\n"); err != nil {
+		t.Fatal(err)
+	}
+	ret, _ = c.Get("default", "web")
+	utiljson.Unmarshal([]byte(ret), &object.Object)
+	if replicas, _, _ := unstructured.NestedInt64(object.Object, "spec", "replicas"); replicas != 5 {
+		t.Errorf("replicas after Update = %d, want 5", replicas)
+	}
+	if err := c.Delete("default", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("default", "web"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+// like the typed clients an empty namespace is sent as is, for List and Watch that means all namespaces
+func TestResourceEmptyNamespace(t *testing.T) {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	var namespaces []string
+	client.PrependReactor("*", "rollouts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		namespaces = append(namespaces, action.GetNamespace())
+		if action.GetVerb() == "list" {
+			return true, &unstructured.UnstructuredList{Object: map[string]interface{}{}}, nil
+		}
+		return false, nil, nil
+	})
+	c := NewForKind(client, newMapper(), "Rollout")
+	if _, err := c.List("", list.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.List("prod", list.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.List("prod", list.Options{AllNamespaces: true}); err != nil {
+		t.Fatal(err)
+	}
+	c.Create("apiVersion: argoproj.io/v1alpha1\nkind: Rollout\nmetadata:\n  name: web\n")
+	if want := []string{"", "prod", "", ""}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("request namespaces = %q, want %q", namespaces, want)
+	}
+}
+
+func TestResourceKindForms(t *testing.T) {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	for _, kind := range []string{"Rollout", "Rollout.argoproj.io", "Rollout.v1alpha1.argoproj.io"} {
+		c := NewForKind(client, newMapper(), kind)
+		mapping, err := c.mapping()
+		if err != nil {
+			t.Errorf("%s: %v", kind, err)
+			continue
+		}
+		if mapping.Resource.Resource != "rollouts" {
+			t.Errorf("%s maps to %s, want rollouts", kind, mapping.Resource)
+		}
+	}
+}
+
+func TestResourceUnknownKind(t *testing.T) {
+	c := NewForKind(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), newMapper(), "Missing.example.com")
+	c.SetClusterName("cluster1")
+	if _, err := c.Get("default", "web"); !errors.Is(err, k8serrors.ErrUnknownResourceType) {
+		t.Errorf("Get: err = %v, want ErrUnknownResourceType", err)
+	}
+}
+
+func TestResourceClusterScoped(t *testing.T) {
+	c := NewForGVK(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), newMapper(), clusterIssuerGVK)
+	// the namespace is dropped for cluster scoped kinds
+	if err := c.Create("apiVersion: cert-manager.io/v1\nkind: ClusterIssuer\nmetadata:\n  name: letsencrypt\n  namespace: default\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("", "letsencrypt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResourceWrongKind(t *testing.T) {
+	c := NewForGVK(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), newMapper(), clusterIssuerGVK)
+	if err := c.Create(rolloutYAML); !errors.Is(err, k8serrors.ErrInvalidManifest) {
+		t.Errorf("Create of a Rollout as ClusterIssuer: err = %v, want ErrInvalidManifest", err)
+	}
+}
+
+func TestResourcePatch(t *testing.T) {
+	c := NewForKind(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), newMapper(), "Rollout")
+	if err := c.Create(rolloutYAML); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := c.Patch("default", "web", types.MergePatchType, "spec:\n  replicas: 3\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := make(map[string]interface{})
+	utiljson.Unmarshal([]byte(ret), &object)
+	if replicas, _, _ := unstructured.NestedInt64(object, "spec", "replicas"); replicas != 3 {
+		t.Errorf("replicas after Patch = %d, want 3", replicas)
+	}
+}
+
+// servers without server-side apply answer 415, Apply then creates and merge patches on the client
+func TestResourceApplyFallback(t *testing.T) {
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("patch", "rollouts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.PatchAction).GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "patch", schema.GroupResource{}, "", "", 0, false)
+	})
+	c := NewForKind(client, newMapper(), "Rollout")
+	ctx, report := apply.WithReport(context.Background())
+	if err := c.ApplyContext(ctx, rolloutYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplyContext(ctx, "apiVersion: argoproj.io/v1alpha1\nkind: Rollout\nmetadata:\n  name: web\n  namespace: default\nspec:\n  replicas: 4\n"); err != nil {
+		t.Fatal(err)
+	}
+	if report.Created() != 1 || report.Updated() != 1 {
+		t.Errorf("report: %d created, %d updated, want 1 and 1", report.Created(), report.Updated())
+	}
+	ret, err := c.Get("default", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := make(map[string]interface{})
+	utiljson.Unmarshal([]byte(ret), &object)
+	if replicas, _, _ := unstructured.NestedInt64(object, "spec", "replicas"); replicas != 4 {
+		t.Errorf("replicas after the second Apply = %d, want 4", replicas)
+	}
+	if _, ok, _ := unstructured.NestedString(object, "metadata", "annotations", apply.LastAppliedAnnotation); !ok {
+		t.Errorf("the last applied annotation is missing")
+	}
+}
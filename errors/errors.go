@@ -0,0 +1,157 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var (
+	ErrClusterNotFound = errors.New("cluster not found")
+	ErrUnknownResourceType = errors.New("unknown resource type")
+	ErrNotFound = errors.New("resource not found")
+	ErrAlreadyExists = errors.New("resource already exists")
+	ErrConflict = errors.New("resource conflict")
+	ErrInvalidManifest = errors.New("invalid manifest")
+	ErrInvalidKubeConfig = errors.New("invalid kubeconfig")
+	// ErrNotSupported is an operation the resource type does not implement
+	ErrNotSupported = errors.New("operation not supported")
+	// ErrExpired is a continue token or resourceVersion the server no longer has (410 Gone), list again from scratch
+	ErrExpired = errors.New("resource version expired")
+)
+
+// Error tells which cluster and object an operation failed on.
+// errors.Is matches Kind, errors.As/Unwrap reach the underlying cause such as an apierrors.StatusError.
+type Error struct {
+	Kind 					error
+	Cluster 				string
+	ResourceType 			string
+	Namespace 				string
+	Name 					string
+	Err 					error
+}
+
+func (e *Error) Error() string {
+	subject := e.ResourceType
+	if len(e.Name) != 0 {
+		name := e.Name
+		if len(e.Namespace) != 0 {
+			name = e.Namespace + "/" + e.Name
+		}
+		subject = strings.TrimSpace(subject + " " + name)
+	}
+	if len(e.Cluster) != 0 {
+		if len(subject) == 0 {
+			subject = "cluster " + e.Cluster
+		} else {
+			subject += " in cluster " + e.Cluster
+		}
+	}
+	cause := ""
+	switch {
+	case e.Err != nil:
+		cause = e.Err.Error()
+	case e.Kind != nil:
+		cause = e.Kind.Error()
+	}
+	if len(subject) == 0 {
+		return cause
+	}
+	return subject + ": " + cause
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// Kind maps an API error to one of the sentinels, or nil when there is no matching one
+func Kind(err error) error {
+	if err == nil {
+		return nil
+	}
+	// the apierrors helpers only look at err itself, find the status error behind retry or url wrappers
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		if statusErr, ok := status.(error); ok {
+			err = statusErr
+		}
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		return ErrNotFound
+	case apierrors.IsAlreadyExists(err):
+		return ErrAlreadyExists
+	case apierrors.IsConflict(err):
+		return ErrConflict
+	case apierrors.IsInvalid(err):
+		return ErrInvalidManifest
+	case apierrors.IsResourceExpired(err), apierrors.IsGone(err):
+		return ErrExpired
+	}
+	return nil
+}
+
+// Wrap annotates err returned by the API server with the object it was about, nil stays nil
+func Wrap(err error, cluster string, resourceType string, namespace string, name string) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{
+		Kind: Kind(err),
+		Cluster: cluster,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		Name: name,
+		Err: err,
+	}
+}
+
+// InvalidManifest reports input that could not be decoded into a resourceType object
+func InvalidManifest(err error, cluster string, resourceType string) error {
+	return &Error{
+		Kind: ErrInvalidManifest,
+		Cluster: cluster,
+		ResourceType: resourceType,
+		Err: err,
+	}
+}
+
+func ClusterNotFound(cluster string) error {
+	return &Error{
+		Kind: ErrClusterNotFound,
+		Cluster: cluster,
+	}
+}
+
+func UnknownResourceType(cluster string, resourceType string) error {
+	return &Error{
+		Kind: ErrUnknownResourceType,
+		Cluster: cluster,
+		ResourceType: resourceType,
+	}
+}
+
+func NotSupported(cluster string, resourceType string, operation string) error {
+	return &Error{
+		Kind: ErrNotSupported,
+		Cluster: cluster,
+		ResourceType: resourceType,
+		Err: errors.New(operation + " is not supported"),
+	}
+}
+
+func InvalidKubeConfig(err error, source string) error {
+	return &Error{
+		Kind: ErrInvalidKubeConfig,
+		ResourceType: "kubeconfig",
+		Name: source,
+		Err: err,
+	}
+}
@@ -1,13 +1,27 @@
 package event
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/api/core/v1"
 	"encoding/json"
 )
 
+const resourceType = "event"
+
 type event struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
 	err 					error
 }
 
@@ -31,11 +45,25 @@ type Event interface {
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
-	List(namespace string, fieldSelector *EventFieldSelector) (eventList []EventInfo, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
+	ListEvents(namespace string, fieldSelector *EventFieldSelector) (eventList []EventInfo, err error)
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
+	ListEventsContext(ctx context.Context, namespace string, fieldSelector *EventFieldSelector) (eventList []EventInfo, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *event {
+func NewForClient(client kubernetes.Interface) *event {
 	return &event{
 		client: client,
 	}
@@ -45,26 +73,83 @@ func (c *event) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *event) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors, nil sends every request once
+func (c *event) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+func (c *event) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *event) Create(input string) (err error) {
-	//todo
-	return
+	return c.CreateContext(context.Background(), input)
+}
+
+// CreateContext is not implemented for events
+func (c *event) CreateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "create")
 }
 
 func (c *event) Update(input string) (err error) {
-	//todo
-	return
+	return c.UpdateContext(context.Background(), input)
+}
+
+// UpdateContext is not implemented for events
+func (c *event) UpdateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "update")
+}
+
+func (c *event) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext is not implemented for events, the multi-document Apply sends them to the dynamic client
+func (c *event) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "apply")
 }
 
 func (c *event) Delete(namespace string, name string) (err error) {
-	//todo
-	return
+	return c.DeleteContext(context.Background(), namespace, name)
+}
+
+// DeleteContext is not implemented for events
+func (c *event) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "delete")
 }
 
 func (c *event) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *event) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	event, err := c.client.CoreV1().Events(namespace).Get(name, metav1.GetOptions{})
+	var event *corev1.Event
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		event, err = c.client.CoreV1().Events(namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
 	d, err := json.Marshal(event)
 	if err != nil {
 		return "", err
@@ -73,7 +158,101 @@ func (c *event) Get(namespace string, name string) (ret string, err error) {
 	return
 }
 
-func (c *event) List(namespace string, fieldSelector *EventFieldSelector) (eventList []EventInfo, err error) {
+func (c *event) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the event with a YAML or JSON body and returns the patched object
+func (c *event) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var event *corev1.Event
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		event, err = c.client.CoreV1().Events(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *event) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of events, options.Limit and options.Continue page through the rest
+func (c *event) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var events *corev1.EventList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		events, err = c.client.CoreV1().Events(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&events.ListMeta)
+	for i := range events.Items {
+		d, err := json.Marshal(&events.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *event) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of events lazily, one request per options.Limit items
+func (c *event) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to events until ctx is done, reconnecting and resuming on its own
+func (c *event) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CoreV1().Events(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.CoreV1().Events(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
+
+func (c *event) ListEvents(namespace string, fieldSelector *EventFieldSelector) (eventList []EventInfo, err error) {
+	return c.ListEventsContext(context.Background(), namespace, fieldSelector)
+}
+
+func (c *event) ListEventsContext(ctx context.Context, namespace string, fieldSelector *EventFieldSelector) (eventList []EventInfo, err error) {
 	if c.err != nil {
 		return nil, c.err
 	}
@@ -101,10 +280,13 @@ func (c *event) List(namespace string, fieldSelector *EventFieldSelector) (event
 			FieldSelector: filter,
 		}
 	}
-	//logger.Info.Println(filter)
-	//logger.Info.Println(opts)
-	events, err := c.client.CoreV1().Events(namespace).List(opts)
+	var events *corev1.EventList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		events, err = c.client.CoreV1().Events(namespace).List(ctx, opts)
+		return
+	})
 	if err != nil {
+		err = c.wrapErr(err, namespace, "")
 		return
 	}
 	for _, event := range events.Items {
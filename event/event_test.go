@@ -0,0 +1,132 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newEvent(name string, reason string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "default",
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind: "Pod",
+			Name: "nginx",
+			Namespace: "default",
+		},
+		Reason: reason,
+		Message: reason + " nginx",
+		Count: 2,
+		Type: corev1.EventTypeNormal,
+	}
+}
+
+func TestEventGetAndList(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newEvent("nginx.1", "Pulled"), newEvent("nginx.2", "Started")))
+	ret, err := c.Get("default", "nginx.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := new(corev1.Event)
+	json.Unmarshal([]byte(ret), event)
+	if event.Reason != "Pulled" {
+		t.Errorf("reason = %s, want Pulled", event.Reason)
+	}
+	if _, err := c.Get("default", "missing"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get missing: err = %v, want ErrNotFound", err)
+	}
+	page, err := c.List("default", list.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("List returned %d items, want 2", len(page.Items))
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newEvent("nginx.1", "Pulled")))
+	eventList, err := c.ListEvents("default", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventList) != 1 {
+		t.Fatalf("ListEvents returned %d events, want 1", len(eventList))
+	}
+	if eventList[0].Reason != "Pulled" || eventList[0].Count != 2 || eventList[0].Type != corev1.EventTypeNormal {
+		t.Errorf("ListEvents = %+v", eventList[0])
+	}
+}
+
+func TestEventPatch(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newEvent("nginx.1", "Pulled")))
+	ret, err := c.Patch("default", "nginx.1", types.MergePatchType, "count: 3\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := new(corev1.Event)
+	json.Unmarshal([]byte(ret), event)
+	if event.Count != 3 {
+		t.Errorf("count after Patch = %d, want 3", event.Count)
+	}
+}
+
+func TestEventWriteIsNotSupported(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newEvent("nginx.1", "Pulled")))
+	c.SetClusterName("cluster1")
+	input := "apiVersion: v1\nkind: Event\nmetadata:\n  name: nginx.1\n  namespace: default\n"
+	if err := c.Create(input); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Create: err = %v, want ErrNotSupported", err)
+	}
+	if err := c.Update(input); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Update: err = %v, want ErrNotSupported", err)
+	}
+	if err := c.Apply(input); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Apply: err = %v, want ErrNotSupported", err)
+	}
+	if err := c.Delete("default", "nginx.1"); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Delete: err = %v, want ErrNotSupported", err)
+	}
+	if _, err := c.Get("default", "nginx.1"); err != nil {
+		t.Errorf("Get after Delete: err = %v, want the event untouched", err)
+	}
+}
+
+func TestEventListRetriesServerErrors(t *testing.T) {
+	client := fake.NewSimpleClientset(newEvent("nginx.1", "Pulled"))
+	attempts := 0
+	client.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, apierrors.NewServiceUnavailable("apiserver restarting")
+		}
+		return false, nil, nil
+	})
+	c := NewForClient(client)
+	c.SetRetryPolicy(&retry.Policy{
+		MaxAttempts: 3,
+		InitialBackoff: time.Millisecond,
+		Multiplier: 1,
+	})
+	eventList, err := c.ListEvents("default", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventList) != 1 || attempts != 2 {
+		t.Errorf("ListEvents returned %d events after %d attempts, want 1 after 2", len(eventList), attempts)
+	}
+}
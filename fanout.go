@@ -0,0 +1,219 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FanOutFunc is the operation run against a single cluster
+type FanOutFunc func(ctx context.Context, clusterName string, client K8SClient) error
+
+type FanOutOptions struct {
+	// Concurrency bounds how many clusters run at once, 0 runs all of them at once
+	Concurrency 			int
+	// FailFast stops starting clusters after the first failure, clusters already running finish
+	FailFast 				bool
+	// Canaries run before every other cluster, the rest is skipped when a canary fails
+	Canaries 				[]string
+}
+
+type ClusterResult struct {
+	ClusterName 			string
+	Success 				bool
+	// Skipped means the operation was never started on the cluster, Err tells why
+	Skipped 				bool
+	Canary 					bool
+	Err 					error
+	StartedAt 				time.Time
+	Duration 				time.Duration
+}
+
+type FanOutReport struct {
+	// Results are in the order the cluster names were given
+	Results 				[]ClusterResult
+	Succeeded 				int
+	Failed 					int
+	Skipped 				int
+	Duration 				time.Duration
+}
+
+var (
+	errCanaryFailed = errors.New("skipped: canary cluster failed")
+	errFailFast = errors.New("skipped: another cluster failed")
+)
+
+type clusterResultJSON struct {
+	Cluster 				string		`json:"cluster"`
+	Success 				bool 		`json:"success"`
+	Skipped 				bool 		`json:"skipped,omitempty"`
+	Canary 					bool 		`json:"canary,omitempty"`
+	Error 					string 		`json:"error,omitempty"`
+	StartedAt 				*time.Time 	`json:"startedAt,omitempty"`
+	Duration 				string 		`json:"duration"`
+	DurationMs 				int64 		`json:"durationMs"`
+}
+
+func (r ClusterResult) MarshalJSON() ([]byte, error) {
+	v := clusterResultJSON{
+		Cluster: r.ClusterName,
+		Success: r.Success,
+		Skipped: r.Skipped,
+		Canary: r.Canary,
+		Duration: r.Duration.String(),
+		DurationMs: r.Duration.Milliseconds(),
+	}
+	if r.Err != nil {
+		v.Error = r.Err.Error()
+	}
+	if !r.StartedAt.IsZero() {
+		v.StartedAt = &r.StartedAt
+	}
+	return json.Marshal(v)
+}
+
+func (r *FanOutReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Results 			[]ClusterResult 	`json:"results"`
+		Succeeded 			int 				`json:"succeeded"`
+		Failed 				int 				`json:"failed"`
+		Skipped 			int 				`json:"skipped"`
+		Duration 			string 				`json:"duration"`
+		DurationMs 			int64 				`json:"durationMs"`
+	}{
+		Results: r.Results,
+		Succeeded: r.Succeeded,
+		Failed: r.Failed,
+		Skipped: r.Skipped,
+		Duration: r.Duration.String(),
+		DurationMs: r.Duration.Milliseconds(),
+	})
+}
+
+// Err returns a *GroupError holding every failed or skipped cluster, nil when all succeeded
+func (r *FanOutReport) Err() error {
+	errs := make(map[string]error)
+	for _, result := range r.Results {
+		if !result.Success {
+			errs[result.ClusterName] = result.Err
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &GroupError{
+		Errors: errs,
+	}
+}
+
+// FanOut runs fn against the named clusters, unknown names fail with ErrClusterNotFound
+func (k *k8sClients) FanOut(ctx context.Context, clusterNames []string, options FanOutOptions, fn FanOutFunc) (report *FanOutReport) {
+	return fanOut(ctx, clusterNames, k.GetClient, options, fn)
+}
+
+func (g *clusterGroup) FanOut(ctx context.Context, options FanOutOptions, fn FanOutFunc) (report *FanOutReport) {
+	return fanOut(ctx, g.clusterNames, g.GetClient, options, fn)
+}
+
+func fanOut(ctx context.Context, clusterNames []string, getClient func(clusterName string) K8SClient, options FanOutOptions, fn FanOutFunc) (report *FanOutReport) {
+	start := time.Now()
+	report = &FanOutReport{
+		Results: make([]ClusterResult, len(clusterNames)),
+	}
+	canaries := make(map[string]bool, len(options.Canaries))
+	for _, name := range options.Canaries {
+		canaries[name] = true
+	}
+	var canaryIndexes, restIndexes []int
+	for i, name := range clusterNames {
+		report.Results[i].ClusterName = name
+		if canaries[name] {
+			report.Results[i].Canary = true
+			canaryIndexes = append(canaryIndexes, i)
+		} else {
+			restIndexes = append(restIndexes, i)
+		}
+	}
+	var (
+		mu sync.Mutex
+		failed bool
+	)
+	stopErr := func() error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("skipped: %w", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if options.FailFast && failed {
+			return errFailFast
+		}
+		return nil
+	}
+	run := func(indexes []int) {
+		concurrency := options.Concurrency
+		if concurrency <= 0 || concurrency > len(indexes) {
+			concurrency = len(indexes)
+		}
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for _, i := range indexes {
+			sem <- struct{}{}
+			if err := stopErr(); err != nil {
+				<-sem
+				report.Results[i].Skipped = true
+				report.Results[i].Err = err
+				continue
+			}
+			wg.Add(1)
+			go func(result *ClusterResult) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				runCluster(ctx, result, getClient, fn)
+				if !result.Success {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}(&report.Results[i])
+		}
+		wg.Wait()
+	}
+	run(canaryIndexes)
+	if failed {
+		for _, i := range restIndexes {
+			report.Results[i].Skipped = true
+			report.Results[i].Err = errCanaryFailed
+		}
+	} else {
+		run(restIndexes)
+	}
+	for _, result := range report.Results {
+		switch {
+		case result.Success:
+			report.Succeeded++
+		case result.Skipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+	report.Duration = time.Since(start)
+	return
+}
+
+func runCluster(ctx context.Context, result *ClusterResult, getClient func(clusterName string) K8SClient, fn FanOutFunc) {
+	result.StartedAt = time.Now()
+	defer func() {
+		// one broken cluster must not take the whole rollout down
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic: %v", r)
+			result.Success = false
+		}
+		result.Duration = time.Since(result.StartedAt)
+	}()
+	result.Err = fn(ctx, result.ClusterName, getClient(result.ClusterName))
+	result.Success = result.Err == nil
+}
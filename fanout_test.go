@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFakeClients(clusterNames ...string) K8SClients {
+	clients := New()
+	for _, name := range clusterNames {
+		clients.Load(name, fake.NewSimpleClientset())
+	}
+	return clients
+}
+
+func TestFanOutConcurrency(t *testing.T) {
+	clusterNames := []string{"c1", "c2", "c3", "c4", "c5", "c6"}
+	clients := newFakeClients(clusterNames...)
+	defer clients.Close()
+	var (
+		mu sync.Mutex
+		running int
+		maxRunning int
+	)
+	report := clients.FanOut(context.Background(), clusterNames, FanOutOptions{Concurrency: 2}, func(ctx context.Context, clusterName string, client K8SClient) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if maxRunning > 2 {
+		t.Errorf("%d clusters ran at once, want at most 2", maxRunning)
+	}
+	if report.Succeeded != len(clusterNames) || report.Err() != nil {
+		t.Errorf("report = %+v, want every cluster succeeded", report)
+	}
+	for i, result := range report.Results {
+		if result.ClusterName != clusterNames[i] || result.StartedAt.IsZero() || result.Duration < 10*time.Millisecond {
+			t.Errorf("result %d = %+v, want %s with its timing", i, result, clusterNames[i])
+		}
+	}
+}
+
+func TestFanOutCanaryFailure(t *testing.T) {
+	clusterNames := []string{"c1", "c2", "canary", "c3"}
+	clients := newFakeClients(clusterNames...)
+	defer clients.Close()
+	var ran []string
+	report := clients.FanOut(context.Background(), clusterNames, FanOutOptions{Canaries: []string{"canary"}}, func(ctx context.Context, clusterName string, client K8SClient) error {
+		ran = append(ran, clusterName)
+		return errors.New("bad rollout")
+	})
+	if len(ran) != 1 || ran[0] != "canary" {
+		t.Errorf("ran on %v, want only the canary", ran)
+	}
+	if report.Failed != 1 || report.Skipped != 3 || !report.Results[2].Canary {
+		t.Errorf("report = %+v, want the canary failed and the rest skipped", report)
+	}
+	if result := report.Results[0]; !result.Skipped || result.Err != errCanaryFailed {
+		t.Errorf("result = %+v, want skipped because of the canary", result)
+	}
+}
+
+func TestFanOutFailFast(t *testing.T) {
+	clusterNames := []string{"c1", "c2", "c3", "c4"}
+	clients := newFakeClients(clusterNames...)
+	defer clients.Close()
+	report := clients.FanOut(context.Background(), clusterNames, FanOutOptions{Concurrency: 1, FailFast: true}, func(ctx context.Context, clusterName string, client K8SClient) error {
+		if clusterName == "c2" {
+			return errors.New("quota exceeded")
+		}
+		return nil
+	})
+	if report.Succeeded != 1 || report.Failed != 1 || report.Skipped != 2 {
+		t.Errorf("report = %+v, want c1 succeeded, c2 failed and the rest skipped", report)
+	}
+	if result := report.Results[3]; !result.Skipped || result.Err != errFailFast {
+		t.Errorf("result = %+v, want skipped after the failure", result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report = clients.FanOut(ctx, clusterNames, FanOutOptions{}, func(ctx context.Context, clusterName string, client K8SClient) error {
+		return nil
+	})
+	if report.Skipped != len(clusterNames) || !errors.Is(report.Results[0].Err, context.Canceled) {
+		t.Errorf("report = %+v, want every cluster skipped by the cancelled context", report)
+	}
+}
+
+func TestFanOutReport(t *testing.T) {
+	clients := newFakeClients("c1", "c2")
+	defer clients.Close()
+	report := clients.FanOut(context.Background(), []string{"c1", "c2", "missing"}, FanOutOptions{}, func(ctx context.Context, clusterName string, client K8SClient) error {
+		if clusterName == "c2" {
+			panic("nil map")
+		}
+		_, err := client.ConfigMap().List("default", ListOptions{})
+		return err
+	})
+	if report.Succeeded != 1 || report.Failed != 2 {
+		t.Errorf("report = %+v, want c1 succeeded and the others failed", report)
+	}
+	if err := report.Results[1].Err; err == nil || !strings.Contains(err.Error(), "panic: nil map") {
+		t.Errorf("c2 err = %v, want the recovered panic", err)
+	}
+	if err := report.Results[2].Err; !errors.Is(err, k8serrors.ErrClusterNotFound) {
+		t.Errorf("missing err = %v, want ErrClusterNotFound", err)
+	}
+	var groupErr *GroupError
+	if err := report.Err(); !errors.As(err, &groupErr) || len(groupErr.Errors) != 2 {
+		t.Errorf("Err() = %v, want a *GroupError for c2 and missing", err)
+	}
+
+	d, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Results 			[]map[string]interface{} 	`json:"results"`
+		Failed 				int 						`json:"failed"`
+	}
+	if err := json.Unmarshal(d, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Failed != 2 || len(decoded.Results) != 3 || decoded.Results[2]["cluster"] != "missing" || decoded.Results[2]["error"] == nil {
+		t.Errorf("json = %s, want the results with cluster names and errors", d)
+	}
+}
@@ -0,0 +1,515 @@
+package k8s
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/service"
+	"github.com/zhanghaohao/kubernetes-client/app"
+	"github.com/zhanghaohao/kubernetes-client/configmap"
+	"github.com/zhanghaohao/kubernetes-client/secret"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterGroup runs the same operation on every cluster matched by Select
+type ClusterGroup interface {
+	Clusters() (clusterNames []string)
+	GetClient(clusterName string) K8SClient
+	AsUser(user string, groups []string, extra map[string][]string) ClusterGroup
+	// CommonResourceObject fans each call out to every cluster, Get and Patch return a JSON object keyed by cluster name
+	// and List pages through the clusters one after another, Watch merges their events
+	CommonResourceObject(resourceObjectType ResourceObjectType) ResourceObject
+	Service() service.Service
+	ConfigMap() configmap.ConfigMap
+	// Deployment, Secret and Job return their extra reads keyed by cluster name
+	Deployment() GroupDeployment
+	Secret() GroupSecret
+	Job() GroupJob
+	// Apply applies the manifest to every cluster, results are keyed by cluster name
+	Apply(input string) (results map[string][]ApplyResult, err error)
+	ApplyContext(ctx context.Context, input string) (results map[string][]ApplyResult, err error)
+	// Each calls fn for every cluster in name order, failures are collected into a *GroupError
+	Each(fn func(clusterName string, client K8SClient) error) (err error)
+	FanOut(ctx context.Context, options FanOutOptions, fn FanOutFunc) (report *FanOutReport)
+}
+
+type GroupDeployment interface {
+	ResourceObject
+	GetStatus(namespace string, deploymentName string) (statuses map[string]*app.DeploymentStatus, err error)
+	GetStatusContext(ctx context.Context, namespace string, deploymentName string) (statuses map[string]*app.DeploymentStatus, err error)
+}
+
+type GroupSecret interface {
+	ResourceObject
+	ListSecrets(namespace string, labelSelector string) (secretLists map[string][]secret.SecretInfo, err error)
+	ListSecretsContext(ctx context.Context, namespace string, labelSelector string) (secretLists map[string][]secret.SecretInfo, err error)
+}
+
+type GroupJob interface {
+	ResourceObject
+	GetStatus(namespace string, jobName string) (statuses map[string]*batchv1.JobStatus, err error)
+	GetStatusContext(ctx context.Context, namespace string, jobName string) (statuses map[string]*batchv1.JobStatus, err error)
+}
+
+// GroupError holds the error of every cluster a group operation failed on
+type GroupError struct {
+	Errors 					map[string]error
+}
+
+func (e *GroupError) Error() string {
+	clusterNames := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		clusterNames = append(clusterNames, name)
+	}
+	sort.Strings(clusterNames)
+	messages := make([]string, 0, len(clusterNames))
+	for _, name := range clusterNames {
+		messages = append(messages, name+": "+e.Errors[name].Error())
+	}
+	return fmt.Sprintf("%d cluster(s) failed: %s", len(clusterNames), strings.Join(messages, "; "))
+}
+
+// clusterGroup keeps the cluster names matched at Select time, the clients are still resolved on every call
+type clusterGroup struct {
+	clients 				*k8sClients
+	clusterNames 			[]string
+	impersonate 			*rest.ImpersonationConfig
+}
+
+type groupResourceObject struct {
+	group 					*clusterGroup
+	resourceObjectType 		ResourceObjectType
+	err 					error
+}
+
+type groupDeployment struct {
+	*groupResourceObject
+}
+
+type groupSecret struct {
+	*groupResourceObject
+}
+
+type groupJob struct {
+	*groupResourceObject
+}
+
+// groupContinue is decoded from the continue token of a group list: the cluster to list next and its own token
+type groupContinue struct {
+	Cluster 				string 		`json:"cluster"`
+	Continue 				string 		`json:"continue,omitempty"`
+}
+
+// Select returns the clusters whose labels match selector, e.g. "env=prod,region in (eu, us)"
+func (k *k8sClients) Select(selector string) (group ClusterGroup, err error) {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster selector %q: %w", selector, err)
+	}
+	return k.SelectLabels(s), nil
+}
+
+func (k *k8sClients) SelectLabels(selector labels.Selector) ClusterGroup {
+	var clusterNames []string
+	k.mu.RLock()
+	for name, c := range k.clients {
+		if selector.Matches(labels.Set(c.metadata.Labels)) {
+			clusterNames = append(clusterNames, name)
+		}
+	}
+	k.mu.RUnlock()
+	sort.Strings(clusterNames)
+	return &clusterGroup{
+		clients: k,
+		clusterNames: clusterNames,
+	}
+}
+
+func (g *clusterGroup) Clusters() (clusterNames []string) {
+	return append([]string(nil), g.clusterNames...)
+}
+
+func (g *clusterGroup) has(clusterName string) bool {
+	for _, name := range g.clusterNames {
+		if name == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *clusterGroup) GetClient(clusterName string) K8SClient {
+	if !g.has(clusterName) {
+		return &k8sClient{
+			clients: g.clients,
+			clusterName: clusterName,
+			err: k8serrors.ClusterNotFound(clusterName),
+		}
+	}
+	client := g.clients.GetClient(clusterName)
+	if g.impersonate != nil {
+		client = client.AsUser(g.impersonate.UserName, g.impersonate.Groups, g.impersonate.Extra)
+	}
+	return client
+}
+
+func (g *clusterGroup) AsUser(user string, groups []string, extra map[string][]string) ClusterGroup {
+	return &clusterGroup{
+		clients: g.clients,
+		clusterNames: g.clusterNames,
+		impersonate: &rest.ImpersonationConfig{
+			UserName: user,
+			Groups: groups,
+			Extra: extra,
+		},
+	}
+}
+
+func (g *clusterGroup) Each(fn func(clusterName string, client K8SClient) error) (err error) {
+	errs := make(map[string]error)
+	for _, name := range g.clusterNames {
+		if clusterErr := fn(name, g.GetClient(name)); clusterErr != nil {
+			errs[name] = clusterErr
+		}
+	}
+	if len(errs) != 0 {
+		return &GroupError{
+			Errors: errs,
+		}
+	}
+	return
+}
+
+func (g *clusterGroup) CommonResourceObject(resourceObjectType ResourceObjectType) ResourceObject {
+	return g.resourceObject(resourceObjectType)
+}
+
+func (g *clusterGroup) Service() service.Service {
+	return g.resourceObject(KubernetesService)
+}
+
+func (g *clusterGroup) ConfigMap() configmap.ConfigMap {
+	return g.resourceObject(KubernetesConfigMap)
+}
+
+func (g *clusterGroup) Deployment() GroupDeployment {
+	return &groupDeployment{g.resourceObject(KubernetesDeployment)}
+}
+
+func (g *clusterGroup) Secret() GroupSecret {
+	return &groupSecret{g.resourceObject(KubernetesSecret)}
+}
+
+func (g *clusterGroup) Job() GroupJob {
+	return &groupJob{g.resourceObject(KubernetesJob)}
+}
+
+func (g *clusterGroup) resourceObject(resourceObjectType ResourceObjectType) *groupResourceObject {
+	return &groupResourceObject{
+		group: g,
+		resourceObjectType: resourceObjectType,
+	}
+}
+
+func (g *clusterGroup) Apply(input string) (results map[string][]ApplyResult, err error) {
+	return g.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext applies the manifest cluster by cluster in name order, a cluster failing does not stop the others
+func (g *clusterGroup) ApplyContext(ctx context.Context, input string) (results map[string][]ApplyResult, err error) {
+	results = make(map[string][]ApplyResult)
+	err = g.Each(func(clusterName string, client K8SClient) error {
+		clusterResults, err := client.ApplyContext(ctx, input)
+		if len(clusterResults) != 0 {
+			results[clusterName] = clusterResults
+		}
+		return err
+	})
+	return
+}
+
+func (o *groupResourceObject) SetErr(err error) {
+	o.err = err
+}
+
+func (o *groupResourceObject) each(fn func(r ResourceObject) error) (err error) {
+	if o.err != nil {
+		return o.err
+	}
+	return o.group.Each(func(clusterName string, client K8SClient) error {
+		return fn(client.CommonResourceObject(o.resourceObjectType))
+	})
+}
+
+func (o *groupResourceObject) Create(input string) (err error) {
+	return o.CreateContext(context.Background(), input)
+}
+
+func (o *groupResourceObject) CreateContext(ctx context.Context, input string) (err error) {
+	return o.each(func(r ResourceObject) error {
+		return r.CreateContext(ctx, input)
+	})
+}
+
+func (o *groupResourceObject) Delete(namespace string, name string) (err error) {
+	return o.DeleteContext(context.Background(), namespace, name)
+}
+
+func (o *groupResourceObject) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
+	return o.each(func(r ResourceObject) error {
+		return r.DeleteContext(ctx, namespace, name)
+	})
+}
+
+func (o *groupResourceObject) Update(input string) (err error) {
+	return o.UpdateContext(context.Background(), input)
+}
+
+func (o *groupResourceObject) UpdateContext(ctx context.Context, input string) (err error) {
+	return o.each(func(r ResourceObject) error {
+		return r.UpdateContext(ctx, input)
+	})
+}
+
+func (o *groupResourceObject) Apply(input string) (err error) {
+	return o.ApplyContext(context.Background(), input)
+}
+
+func (o *groupResourceObject) ApplyContext(ctx context.Context, input string) (err error) {
+	return o.each(func(r ResourceObject) error {
+		return r.ApplyContext(ctx, input)
+	})
+}
+
+func (o *groupResourceObject) Get(namespace string, name string) (ret string, err error) {
+	return o.GetContext(context.Background(), namespace, name)
+}
+
+// GetContext returns the objects found as {"cluster1": {...}, ...}, clusters that failed are left out
+func (o *groupResourceObject) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
+	if o.err != nil {
+		return "", o.err
+	}
+	objects := make(map[string]json.RawMessage)
+	err = o.group.Each(func(clusterName string, client K8SClient) error {
+		object, err := client.CommonResourceObject(o.resourceObjectType).GetContext(ctx, namespace, name)
+		if err != nil {
+			return err
+		}
+		objects[clusterName] = json.RawMessage(object)
+		return nil
+	})
+	d, marshalErr := json.Marshal(objects)
+	if marshalErr != nil {
+		return "", marshalErr
+	}
+	ret = string(d)
+	return
+}
+
+func (o *groupResourceObject) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return o.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext returns the patched objects as {"cluster1": {...}, ...}, clusters that failed are left out
+func (o *groupResourceObject) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if o.err != nil {
+		return "", o.err
+	}
+	objects := make(map[string]json.RawMessage)
+	err = o.group.Each(func(clusterName string, client K8SClient) error {
+		object, err := client.CommonResourceObject(o.resourceObjectType).PatchContext(ctx, namespace, name, patchType, input)
+		if err != nil {
+			return err
+		}
+		objects[clusterName] = json.RawMessage(object)
+		return nil
+	})
+	d, marshalErr := json.Marshal(objects)
+	if marshalErr != nil {
+		return "", marshalErr
+	}
+	ret = string(d)
+	return
+}
+
+func (o *groupResourceObject) List(namespace string, options ListOptions) (page *ListPage, err error) {
+	return o.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext lists the clusters one after another in name order, so every page holds objects of a
+// single cluster. Page.Continue moves on to the next cluster once the current one is exhausted.
+func (o *groupResourceObject) ListContext(ctx context.Context, namespace string, options ListOptions) (page *ListPage, err error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	clusterNames := o.group.Clusters()
+	if len(clusterNames) == 0 {
+		return &ListPage{}, nil
+	}
+	position := groupContinue{
+		Cluster: clusterNames[0],
+	}
+	if len(options.Continue) != 0 {
+		d, err := base64.RawURLEncoding.DecodeString(options.Continue)
+		if err == nil {
+			err = json.Unmarshal(d, &position)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid continue token: %w", err)
+		}
+	}
+	index := sort.SearchStrings(clusterNames, position.Cluster)
+	if index == len(clusterNames) || clusterNames[index] != position.Cluster {
+		return nil, k8serrors.ClusterNotFound(position.Cluster)
+	}
+	options.Continue = position.Continue
+	page, err = o.group.GetClient(position.Cluster).CommonResourceObject(o.resourceObjectType).ListContext(ctx, namespace, options)
+	if err != nil {
+		return nil, err
+	}
+	// the count only covers the current cluster
+	page.RemainingItemCount = nil
+	next := groupContinue{
+		Cluster: position.Cluster,
+		Continue: page.Continue,
+	}
+	if len(page.Continue) == 0 {
+		if index == len(clusterNames)-1 {
+			return
+		}
+		next = groupContinue{
+			Cluster: clusterNames[index+1],
+		}
+	}
+	d, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+	page.Continue = base64.RawURLEncoding.EncodeToString(d)
+	return
+}
+
+func (o *groupResourceObject) Iterate(namespace string, options ListOptions) *list.Iterator {
+	return o.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every cluster's objects lazily, cluster by cluster
+func (o *groupResourceObject) IterateContext(ctx context.Context, namespace string, options ListOptions) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return o.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch merges the watches of every cluster into one channel, Event.ClusterName tells them apart.
+// If any cluster cannot be watched none is and the error is a *GroupError.
+func (o *groupResourceObject) Watch(ctx context.Context, namespace string, options WatchOptions) (events <-chan WatchEvent, err error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var streams []<-chan watch.Event
+	err = o.group.Each(func(clusterName string, client K8SClient) error {
+		stream, err := client.CommonResourceObject(o.resourceObjectType).Watch(ctx, namespace, options)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, stream)
+		return nil
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	merged := make(chan watch.Event)
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func(stream <-chan watch.Event) {
+			defer wg.Done()
+			for event := range stream {
+				select {
+				case merged <- event:
+				case <-ctx.Done():
+				}
+			}
+		}(stream)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(merged)
+	}()
+	return merged, nil
+}
+
+func (o *groupDeployment) GetStatus(namespace string, deploymentName string) (statuses map[string]*app.DeploymentStatus, err error) {
+	return o.GetStatusContext(context.Background(), namespace, deploymentName)
+}
+
+// GetStatusContext returns the status of every cluster the deployment was read from, clusters that failed are left out
+func (o *groupDeployment) GetStatusContext(ctx context.Context, namespace string, deploymentName string) (statuses map[string]*app.DeploymentStatus, err error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	statuses = make(map[string]*app.DeploymentStatus)
+	err = o.group.Each(func(clusterName string, client K8SClient) error {
+		status, err := client.Deployment().GetStatusContext(ctx, namespace, deploymentName)
+		if err != nil {
+			return err
+		}
+		statuses[clusterName] = status
+		return nil
+	})
+	return
+}
+
+func (o *groupSecret) ListSecrets(namespace string, labelSelector string) (secretLists map[string][]secret.SecretInfo, err error) {
+	return o.ListSecretsContext(context.Background(), namespace, labelSelector)
+}
+
+func (o *groupSecret) ListSecretsContext(ctx context.Context, namespace string, labelSelector string) (secretLists map[string][]secret.SecretInfo, err error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	secretLists = make(map[string][]secret.SecretInfo)
+	err = o.group.Each(func(clusterName string, client K8SClient) error {
+		secretList, err := client.Secret().ListSecretsContext(ctx, namespace, labelSelector)
+		if err != nil {
+			return err
+		}
+		secretLists[clusterName] = secretList
+		return nil
+	})
+	return
+}
+
+func (o *groupJob) GetStatus(namespace string, jobName string) (statuses map[string]*batchv1.JobStatus, err error) {
+	return o.GetStatusContext(context.Background(), namespace, jobName)
+}
+
+func (o *groupJob) GetStatusContext(ctx context.Context, namespace string, jobName string) (statuses map[string]*batchv1.JobStatus, err error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	statuses = make(map[string]*batchv1.JobStatus)
+	err = o.group.Each(func(clusterName string, client K8SClient) error {
+		status, err := client.Job().GetStatusContext(ctx, namespace, jobName)
+		if err != nil {
+			return err
+		}
+		statuses[clusterName] = status
+		return nil
+	})
+	return
+}
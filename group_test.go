@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newLabeledClients() K8SClients {
+	clients := New(WithClusterLabels("prod-us", map[string]string{"env": "prod", "region": "us"}))
+	clients.LoadWithMetadata("prod-eu", fake.NewSimpleClientset(), ClusterMetadata{
+		Labels: map[string]string{"env": "prod", "region": "eu"},
+	})
+	clients.Load("prod-us", fake.NewSimpleClientset())
+	clients.LoadWithMetadata("test-eu", fake.NewSimpleClientset(), ClusterMetadata{
+		Labels: map[string]string{"env": "test", "region": "eu"},
+	})
+	return clients
+}
+
+func TestSelect(t *testing.T) {
+	clients := newLabeledClients()
+	defer clients.Close()
+	for selector, want := range map[string][]string{
+		"env=prod": {"prod-eu", "prod-us"},
+		"region=eu": {"prod-eu", "test-eu"},
+		"env=prod,region in (eu)": {"prod-eu"},
+		"env!=prod": {"test-eu"},
+		"team=payments": nil,
+	} {
+		group, err := clients.Select(selector)
+		if err != nil {
+			t.Errorf("Select(%q): %v", selector, err)
+			continue
+		}
+		if names := group.Clusters(); !reflect.DeepEqual(names, want) {
+			t.Errorf("Select(%q) = %v, want %v", selector, names, want)
+		}
+	}
+	if _, err := clients.Select("env in (prod"); err == nil {
+		t.Error("invalid selector: err = nil")
+	}
+	group, _ := clients.Select("env=prod")
+	if _, err := group.GetClient("test-eu").ConfigMap().Get("default", "settings"); !errors.Is(err, k8serrors.ErrClusterNotFound) {
+		t.Errorf("client outside the group: err = %v, want ErrClusterNotFound", err)
+	}
+}
+
+const settingsConfigMap = `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"default"},"data":{"level":"info"}}`
+
+func TestClusterGroupResourceObject(t *testing.T) {
+	clients := newLabeledClients()
+	defer clients.Close()
+	group, _ := clients.Select("env=prod")
+	configMaps := group.CommonResourceObject(KubernetesConfigMap)
+	if err := configMaps.Create(settingsConfigMap); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clients.GetClient("test-eu").ConfigMap().Get("default", "settings"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("test-eu: err = %v, want the config map only created in the group", err)
+	}
+	ret, err := configMaps.Get("default", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(ret), &objects); err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects["prod-eu"] == nil || objects["prod-us"] == nil {
+		t.Errorf("Get = %s, want the object keyed by prod-eu and prod-us", ret)
+	}
+
+	// a cluster failing does not stop the others, its error is reported by name
+	if err := clients.GetClient("prod-us").ConfigMap().Delete("default", "settings"); err != nil {
+		t.Fatal(err)
+	}
+	ret, err = configMaps.Get("default", "settings")
+	var groupErr *GroupError
+	if !errors.As(err, &groupErr) || len(groupErr.Errors) != 1 || !errors.Is(groupErr.Errors["prod-us"], k8serrors.ErrNotFound) {
+		t.Errorf("err = %v, want a *GroupError for prod-us only", err)
+	}
+	objects = make(map[string]json.RawMessage)
+	json.Unmarshal([]byte(ret), &objects)
+	if len(objects) != 1 || objects["prod-eu"] == nil {
+		t.Errorf("Get = %s, want prod-eu's object", ret)
+	}
+}
+
+func TestClusterGroupIterate(t *testing.T) {
+	clients := newLabeledClients()
+	defer clients.Close()
+	for _, name := range []string{"prod-eu", "prod-us", "test-eu"} {
+		if err := clients.GetClient(name).ConfigMap().Create(settingsConfigMap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	group, _ := clients.Select("region=eu")
+	it := group.ConfigMap().Iterate("default", ListOptions{})
+	items := 0
+	for it.Next() {
+		items++
+	}
+	if it.Err() != nil || items != 2 {
+		t.Errorf("iterated %d items, err = %v, want one config map from each eu cluster", items, it.Err())
+	}
+	if _, err := group.ConfigMap().List("default", ListOptions{Continue: "not a token"}); err == nil {
+		t.Error("bad continue token: err = nil")
+	}
+}
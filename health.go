@@ -0,0 +1,224 @@
+package k8s
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+)
+
+const defaultHealthCheckInterval = 30 * time.Second
+
+type AuthStatus string
+
+const (
+	AuthOK AuthStatus = "OK"
+	AuthUnauthorized AuthStatus = "Unauthorized"
+	AuthForbidden AuthStatus = "Forbidden"
+	AuthCertificateExpired AuthStatus = "CertificateExpired"
+	AuthCertificateInvalid AuthStatus = "CertificateInvalid"
+	AuthUnknown AuthStatus = "Unknown"
+)
+
+type ClusterHealth struct {
+	ClusterName 			string
+	Reachable 				bool
+	// Healthy means reachable and authenticated
+	Healthy 				bool
+	Latency 				time.Duration
+	ServerVersion 			string
+	AuthStatus 				AuthStatus
+	Err 					error
+	CheckedAt 				time.Time
+}
+
+// Ping checks a single cluster, the probe is bounded by ctx
+func (k *k8sClients) Ping(ctx context.Context, clusterName string) (health ClusterHealth) {
+	health, _ = k.ping(ctx, clusterName)
+	return
+}
+
+// ping also returns the cluster it probed, nil when it is not registered
+func (k *k8sClients) ping(ctx context.Context, clusterName string) (health ClusterHealth, c *cluster) {
+	health.ClusterName = clusterName
+	health.AuthStatus = AuthUnknown
+	health.CheckedAt = time.Now()
+	k.mu.RLock()
+	c, ok := k.clients[clusterName]
+	k.mu.RUnlock()
+	if !ok {
+		health.Err = k8serrors.ClusterNotFound(clusterName)
+		return
+	}
+	probeCluster(ctx, c.client, &health)
+	return
+}
+
+// Health checks every registered cluster concurrently, the probes are bounded by ctx
+func (k *k8sClients) Health(ctx context.Context) map[string]ClusterHealth {
+	results, _ := k.probeAll(ctx)
+	return results
+}
+
+func (k *k8sClients) probeAll(ctx context.Context) (results map[string]ClusterHealth, probed map[string]*cluster) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	results = make(map[string]ClusterHealth)
+	probed = make(map[string]*cluster)
+	for _, name := range k.List() {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			health, c := k.ping(ctx, name)
+			mu.Lock()
+			results[name] = health
+			probed[name] = c
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return
+}
+
+// StartHealthChecks probes all clusters every interval and caches the results for CachedHealth
+// and for GetClient when WithRefuseUnhealthy is set. The returned stop func (or Close) ends the loop.
+func (k *k8sClients) StartHealthChecks(interval time.Duration, timeout time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	if timeout <= 0 || timeout > interval {
+		timeout = interval
+	}
+	stopCh := make(chan struct{})
+	var once sync.Once
+	check := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		results, probed := k.probeAll(ctx)
+		k.mu.Lock()
+		for name, health := range results {
+			// the cluster may have been removed or replaced while probing, a reloaded client starts unprobed
+			if c, ok := k.clients[name]; ok && c == probed[name] {
+				k.health[name] = health
+			}
+		}
+		k.mu.Unlock()
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		check()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+	stop = func() {
+		once.Do(func() {
+			close(stopCh)
+		})
+	}
+	k.addStop(stop)
+	return
+}
+
+func (k *k8sClients) CachedHealth(clusterName string) (health ClusterHealth, ok bool) {
+	k.mu.RLock()
+	health, ok = k.health[clusterName]
+	k.mu.RUnlock()
+	return
+}
+
+func probeCluster(ctx context.Context, client kubernetes.Interface, health *ClusterHealth) {
+	/*
+	/version is readable anonymously, so it tells reachability and version;
+	/api needs an authenticated user, so it tells whether our credentials work
+	 */
+	restClient := client.Discovery().RESTClient()
+	if restClient == nil {
+		// fake clientsets have no REST client behind discovery
+		probeDiscovery(client, health)
+		return
+	}
+	start := time.Now()
+	body, err := restClient.Get().AbsPath("/version").DoRaw(ctx)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Reachable = isReachableErr(err)
+		health.AuthStatus = authStatusFromErr(err)
+		health.Err = err
+		return
+	}
+	health.Reachable = true
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err == nil {
+		health.ServerVersion = info.GitVersion
+	}
+	_, err = restClient.Get().AbsPath("/api").DoRaw(ctx)
+	if err != nil {
+		health.AuthStatus = authStatusFromErr(err)
+		health.Err = err
+		return
+	}
+	health.AuthStatus = AuthOK
+	health.Healthy = true
+}
+
+func probeDiscovery(client kubernetes.Interface, health *ClusterHealth) {
+	start := time.Now()
+	info, err := client.Discovery().ServerVersion()
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Reachable = isReachableErr(err)
+		health.AuthStatus = authStatusFromErr(err)
+		health.Err = err
+		return
+	}
+	health.Reachable = true
+	health.ServerVersion = info.GitVersion
+	health.AuthStatus = AuthOK
+	health.Healthy = true
+}
+
+func isReachableErr(err error) bool {
+	// any answer from the API server, even an error status, means it is reachable
+	_, ok := err.(apierrors.APIStatus)
+	return ok
+}
+
+func authStatusFromErr(err error) AuthStatus {
+	switch {
+	case apierrors.IsUnauthorized(err):
+		return AuthUnauthorized
+	case apierrors.IsForbidden(err):
+		return AuthForbidden
+	}
+	var certErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) {
+		if certErr.Reason == x509.Expired {
+			return AuthCertificateExpired
+		}
+		return AuthCertificateInvalid
+	}
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(err, &authorityErr) {
+		return AuthCertificateInvalid
+	}
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return AuthCertificateInvalid
+	}
+	return AuthUnknown
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFakeClientset(gitVersion string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: gitVersion,
+	}
+	return client
+}
+
+// fake clientsets have no REST client behind discovery, Ping asks discovery for the version instead
+func TestPingFakeClientset(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clients.Load("cluster1", newFakeClientset("v1.18.3"))
+	health := clients.Ping(context.Background(), "cluster1")
+	if health.Err != nil {
+		t.Fatal(health.Err)
+	}
+	if !health.Reachable || !health.Healthy || health.AuthStatus != AuthOK {
+		t.Errorf("Ping = %+v, want reachable, healthy and authenticated", health)
+	}
+	if health.ServerVersion != "v1.18.3" {
+		t.Errorf("ServerVersion = %s, want v1.18.3", health.ServerVersion)
+	}
+	if health.ClusterName != "cluster1" || health.CheckedAt.IsZero() {
+		t.Errorf("Ping = %+v, want ClusterName and CheckedAt set", health)
+	}
+}
+
+func TestPingMissingCluster(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	health := clients.Ping(context.Background(), "missing")
+	if !errors.Is(health.Err, ErrClusterNotFound) {
+		t.Errorf("Ping missing: err = %v, want ErrClusterNotFound", health.Err)
+	}
+	if health.Reachable || health.Healthy || health.AuthStatus != AuthUnknown {
+		t.Errorf("Ping missing = %+v, want unreachable with unknown auth", health)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clients.Load("cluster1", newFakeClientset("v1.18.3"))
+	clients.Load("cluster2", newFakeClientset("v1.20.0"))
+	results := clients.Health(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("Health returned %d results, want 2", len(results))
+	}
+	if results["cluster2"].ServerVersion != "v1.20.0" || !results["cluster2"].Healthy {
+		t.Errorf("cluster2 = %+v", results["cluster2"])
+	}
+}
+
+func TestStartHealthChecks(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clients.Load("cluster1", newFakeClientset("v1.18.3"))
+	stop := clients.StartHealthChecks(time.Hour, time.Second)
+	defer stop()
+	// the first check runs right away in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if health, ok := clients.CachedHealth("cluster1"); ok {
+			if !health.Healthy {
+				t.Errorf("cached health = %+v, want healthy", health)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no cached health before the deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// a reloaded cluster starts unprobed
+	clients.Load("cluster1", newFakeClientset("v1.19.0"))
+	if _, ok := clients.CachedHealth("cluster1"); ok {
+		t.Error("cached health survived Load")
+	}
+}
@@ -11,7 +11,25 @@ import (
 	"github.com/zhanghaohao/kubernetes-client/secret"
 	"k8s.io/client-go/kubernetes"
 	"github.com/zhanghaohao/kubernetes-client/batch"
+	"github.com/zhanghaohao/kubernetes-client/dynamic"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientdynamic "k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 )
 
@@ -30,6 +48,31 @@ const (
 
 type ResourceObjectType string
 
+type ContextFilter = k8sconfig.ContextFilter
+
+// Error and the sentinels below are shared by every package, use errors.Is/errors.As to inspect them
+type Error = k8serrors.Error
+
+// ListOptions and ListPage are the list package's types, shared by List on every resource object
+type ListOptions = list.Options
+type ListPage = list.Page
+
+// WatchOptions and WatchEvent are the watch package's types, shared by Watch on every resource object
+type WatchOptions = watch.Options
+type WatchEvent = watch.Event
+
+var (
+	ErrClusterNotFound = k8serrors.ErrClusterNotFound
+	ErrUnknownResourceType = k8serrors.ErrUnknownResourceType
+	ErrNotFound = k8serrors.ErrNotFound
+	ErrAlreadyExists = k8serrors.ErrAlreadyExists
+	ErrConflict = k8serrors.ErrConflict
+	ErrInvalidManifest = k8serrors.ErrInvalidManifest
+	ErrInvalidKubeConfig = k8serrors.ErrInvalidKubeConfig
+	ErrNotSupported = k8serrors.ErrNotSupported
+	ErrExpired = k8serrors.ErrExpired
+)
+
 type ResourceObjectRegister map[ResourceObjectType]ResourceObject
 
 type KubeConfigPath struct {
@@ -37,26 +80,64 @@ type KubeConfigPath struct {
 	Path 					string
 }
 
+type KubeConfigData struct {
+	ClusterName 			string
+	Data 					[]byte
+}
+
+type KubeConfigBase64 struct {
+	ClusterName 			string
+	Data 					string
+}
+
+type KubeConfigEnv struct {
+	ClusterName 			string
+	// EnvName is the environment variable holding the kubeconfig, raw or base64 encoded
+	EnvName 				string
+}
+
 type RestKubeConfig struct {
 	ClusterName 			string
 	KubeConfig				*rest.Config
 }
 
+// k8sClient resolves the cluster's clientset on every call, so it follows reloads and removals
 type k8sClient struct {
-	client 					*kubernetes.Clientset
+	clients 				*k8sClients
+	clusterName 			string
+	// impersonate is set by AsUser
+	impersonate 			*rest.ImpersonationConfig
 	err 					error
 }
 
-type k8sClients struct {
-	clients 				map[string]*kubernetes.Clientset
-}
-
 type K8SClients interface {
 	GetClient(clusterName string) K8SClient
-	Load(clusterName string, client *kubernetes.Clientset)
+	// Load registers client under clusterName, replacing any client already registered
+	Load(clusterName string, client kubernetes.Interface)
+	LoadWithMetadata(clusterName string, client kubernetes.Interface, metadata ClusterMetadata)
+	Remove(clusterName string) (ok bool)
+	List() (clusterNames []string)
+	Has(clusterName string) bool
+	Metadata(clusterName string) (metadata ClusterMetadata, ok bool)
+	SetLabels(clusterName string, labels map[string]string) (ok bool)
+	// Select returns the clusters whose labels match a label selector such as "env=prod"
+	Select(selector string) (group ClusterGroup, err error)
+	SelectLabels(selector labels.Selector) ClusterGroup
+	// FanOut runs fn against many clusters concurrently and reports the result of each
+	FanOut(ctx context.Context, clusterNames []string, options FanOutOptions, fn FanOutFunc) (report *FanOutReport)
+	WatchKubeconfigPaths(paths []KubeConfigPath, interval time.Duration, onReload ReloadFunc) (stop func())
+	WatchKubeconfigDir(dir KubeConfigDir, interval time.Duration, onReload ReloadFunc) (stop func())
+	WatchSecrets(source SecretSource, interval time.Duration, onReload ReloadFunc) (stop func())
+	Ping(ctx context.Context, clusterName string) (health ClusterHealth)
+	Health(ctx context.Context) map[string]ClusterHealth
+	StartHealthChecks(interval time.Duration, timeout time.Duration) (stop func())
+	CachedHealth(clusterName string) (health ClusterHealth, ok bool)
+	Close()
 }
 
 type K8SClient interface {
+	// AsUser returns a client whose requests impersonate user, so RBAC is enforced as that user
+	AsUser(user string, groups []string, extra map[string][]string) K8SClient
 	CommonResourceObject(resourceObjectType ResourceObjectType) ResourceObject
 	Service() service.Service
 	Pod() pod.Pod
@@ -66,6 +147,11 @@ type K8SClient interface {
 	ConfigMap() configmap.ConfigMap
 	Secret() secret.Secret
 	Job() batch.Job
+	Dynamic(gvk schema.GroupVersionKind) dynamic.Resource
+	DynamicKind(kind string) dynamic.Resource
+	// Apply creates or updates every document of a ---separated manifest through the resource object for its kind
+	Apply(input string) (results []ApplyResult, err error)
+	ApplyContext(ctx context.Context, input string) (results []ApplyResult, err error)
 }
 
 type ResourceObject interface {
@@ -73,28 +159,52 @@ type ResourceObject interface {
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options ListOptions) (page *ListPage, err error)
+	Iterate(namespace string, options ListOptions) *list.Iterator
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options ListOptions) (page *ListPage, err error)
+	IterateContext(ctx context.Context, namespace string, options ListOptions) *list.Iterator
+	Watch(ctx context.Context, namespace string, options WatchOptions) (events <-chan WatchEvent, err error)
 }
 
 func (c ResourceObjectType) String() string {
 	return string(c)
 }
 
-func NewFromDefaultKubeconfigPaths() (clients K8SClients, err error) {
+// New returns an empty cluster registry, clusters are added later with Load
+func New(opts ...Option) K8SClients {
+	return newK8sClients(opts...)
+}
+
+func NewFromDefaultKubeconfigPaths(opts ...Option) (clients K8SClients, err error) {
+	/*
+	fall back to the pod's service account when the default kubeconfig is missing
+	 */
+	if _, statErr := os.Stat(defaultKubeConfigPath); os.IsNotExist(statErr) && k8sconfig.IsInCluster(k8sconfig.DefaultServiceAccountDir) {
+		return NewFromInClusterConfig(opts...)
+	}
 	paths := []KubeConfigPath{
 		{
 			ClusterName: defaultClusterName,
 			Path: defaultKubeConfigPath,
 		},
 	}
-	clients, err = NewFromKubeconfigPaths(paths)
+	clients, err = NewFromKubeconfigPaths(paths, opts...)
 	if err != nil {
 		return
 	}
 	return
 }
 
-func NewFromKubeconfigPaths(paths []KubeConfigPath) (clients K8SClients, err error) {
+func NewFromKubeconfigPaths(paths []KubeConfigPath, opts ...Option) (clients K8SClients, err error) {
 	/*
 	build k8s clients from kubeConfig file paths
 	 */
@@ -102,126 +212,553 @@ func NewFromKubeconfigPaths(paths []KubeConfigPath) (clients K8SClients, err err
 		err := fmt.Errorf("empty kubeconfig paths provided")
 		return nil, err
 	}
+	c := newK8sClients(opts...)
 	for _, path := range paths {
-		client, err := k8sconfig.BuildKubernetesClientFromKubeConfigFile(path.Path)
+		kubeConfig, err := k8sconfig.ReadKubeConfigFile(path.Path)
+		if err != nil {
+			return nil, err
+		}
+		err = c.loadConfig(path.ClusterName, kubeConfig, ClusterMetadata{
+			Source: path.Path,
+		})
 		if err != nil {
 			return nil, err
 		}
-		clients.Load(path.ClusterName, client)
 	}
+	clients = c
 	return
 }
 
-func NewFromKubeconfigs(configs []RestKubeConfig) (clients K8SClients, err error) {
+func NewFromKubeconfigs(configs []RestKubeConfig, opts ...Option) (clients K8SClients, err error) {
 	/*
 	build k8s clients from rest kubeConfigs
 	 */
+	c := newK8sClients(opts...)
 	for _, config := range configs {
-		client, err := k8sconfig.BuildKubernetesClientFromKubeConfig(config.KubeConfig)
+		err := c.loadConfig(config.ClusterName, config.KubeConfig, ClusterMetadata{
+			Server: config.KubeConfig.Host,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	clients = c
+	return
+}
+
+func NewFromKubeconfigData(configs []KubeConfigData, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build k8s clients from in-memory kubeconfig YAML
+	 */
+	if len(configs) == 0 {
+		err := fmt.Errorf("empty kubeconfigs provided")
+		return nil, err
+	}
+	c := newK8sClients(opts...)
+	for _, config := range configs {
+		kubeConfig, err := k8sconfig.ReadKubeConfigBytes(config.Data)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", config.ClusterName, err)
+		}
+		err = c.loadConfig(config.ClusterName, kubeConfig, ClusterMetadata{
+			Source: "data",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	clients = c
+	return
+}
+
+func NewFromKubeconfigBase64(configs []KubeConfigBase64, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build k8s clients from base64 encoded kubeconfigs
+	 */
+	if len(configs) == 0 {
+		err := fmt.Errorf("empty kubeconfigs provided")
+		return nil, err
+	}
+	c := newK8sClients(opts...)
+	for _, config := range configs {
+		data, err := k8sconfig.DecodeKubeConfigBase64(config.Data)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", config.ClusterName, err)
+		}
+		kubeConfig, err := k8sconfig.ReadKubeConfigBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", config.ClusterName, err)
+		}
+		err = c.loadConfig(config.ClusterName, kubeConfig, ClusterMetadata{
+			Source: "base64",
+		})
 		if err != nil {
 			return nil, err
 		}
-		clients.Load(config.ClusterName, client)
 	}
+	clients = c
 	return
 }
 
-func (k *k8sClients) GetClient(clusterName string) K8SClient {
-	r := new(k8sClient)
-	client, ok := k.clients[clusterName]
+func NewFromKubeconfigEnvVars(configs []KubeConfigEnv, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build k8s clients from kubeconfigs held in environment variables
+	 */
+	if len(configs) == 0 {
+		err := fmt.Errorf("empty kubeconfig environment variables provided")
+		return nil, err
+	}
+	c := newK8sClients(opts...)
+	for _, config := range configs {
+		data, err := k8sconfig.ReadKubeConfigEnvData(config.EnvName)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", config.ClusterName, err)
+		}
+		kubeConfig, err := k8sconfig.ReadKubeConfigBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", config.ClusterName, err)
+		}
+		err = c.loadConfig(config.ClusterName, kubeConfig, ClusterMetadata{
+			Source: "env:" + config.EnvName,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	clients = c
+	return
+}
+
+func NewFromKubeconfigContexts(kubeConfigPaths []string, filter *ContextFilter, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build one k8s client per context of the merged kubeconfig files,
+	each registered under its context name
+	 */
+	configs, err := k8sconfig.ReadKubeConfigContexts(kubeConfigPaths, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		err := fmt.Errorf("no kubeconfig context matched in %v", kubeConfigPaths)
+		return nil, err
+	}
+	c := newK8sClients(opts...)
+	for _, config := range configs {
+		err := c.loadConfig(config.ContextName, config.KubeConfig, ClusterMetadata{
+			Source: strings.Join(kubeConfigPaths, string(filepath.ListSeparator)),
+			Server: config.KubeConfig.Host,
+			Context: config.ContextName,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	clients = c
+	return
+}
+
+func NewFromKubeconfigEnv(filter *ContextFilter, opts ...Option) (clients K8SClients, err error) {
+	/*
+	same as NewFromKubeconfigContexts, using the colon-separated KUBECONFIG files
+	 */
+	return NewFromKubeconfigContexts(k8sconfig.KubeConfigPathsFromEnv(), filter, opts...)
+}
+
+func NewFromInClusterConfig(opts ...Option) (clients K8SClients, err error) {
+	return NewFromServiceAccountDir(k8sconfig.DefaultServiceAccountDir, opts...)
+}
+
+func NewFromServiceAccountDir(serviceAccountDir string, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build k8s client from the pod's service account token, CA bundle and
+	KUBERNETES_SERVICE_HOST/PORT, registered under the default cluster name
+	 */
+	c := newK8sClients(opts...)
+	kubeConfig, err := k8sconfig.ReadInClusterConfig(serviceAccountDir)
+	if err != nil {
+		return nil, err
+	}
+	err = c.loadConfig(defaultClusterName, kubeConfig, ClusterMetadata{
+		Source: serviceAccountDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	clients = c
+	return
+}
+
+func (k *k8sClient) cluster() (c *cluster, err error) {
+	if k.err != nil {
+		return nil, k.err
+	}
+	k.clients.mu.RLock()
+	c, ok := k.clients.clients[k.clusterName]
+	k.clients.mu.RUnlock()
 	if !ok {
-		r.err = fmt.Errorf("invalid clusterName %s", clusterName)
-		return r
+		return nil, k8serrors.ClusterNotFound(k.clusterName)
+	}
+	return
+}
+
+func (k *k8sClient) clientset() (client kubernetes.Interface, err error) {
+	c, err := k.cluster()
+	if err != nil {
+		return
+	}
+	if k.impersonate != nil {
+		return c.impersonatedClient(k.clusterName, *k.impersonate)
+	}
+	return c.client, nil
+}
+
+func (k *k8sClient) dynamicClient() (client clientdynamic.Interface, mapper meta.RESTMapper, err error) {
+	c, err := k.cluster()
+	if err != nil {
+		return
+	}
+	mapper = c.restMapper()
+	if k.impersonate != nil {
+		client, err = c.impersonatedDynamic(k.clusterName, *k.impersonate)
+		return
+	}
+	if c.dynamic == nil {
+		err = fmt.Errorf("cluster %s was loaded without a rest config, the dynamic client needs a cluster built by a New* constructor", k.clusterName)
+		return
+	}
+	return c.dynamic, mapper, nil
+}
+
+func (k *k8sClient) AsUser(user string, groups []string, extra map[string][]string) K8SClient {
+	impersonate := &rest.ImpersonationConfig{
+		UserName: user,
+		Groups: append([]string(nil), groups...),
+		Extra: make(map[string][]string, len(extra)),
+	}
+	for key, values := range extra {
+		impersonate.Extra[key] = append([]string(nil), values...)
+	}
+	r := &k8sClient{
+		clients: k.clients,
+		clusterName: k.clusterName,
+		impersonate: impersonate,
+		err: k.err,
+	}
+	if len(user) == 0 {
+		r.err = fmt.Errorf("cluster %s: impersonation needs a user name", k.clusterName)
 	}
-	r.client = client
 	return r
 }
 
-func (k *k8sClients) Load(clusterName string, client *kubernetes.Clientset) {
-	k.clients[clusterName] = client
+// ResourceObjectFactory builds the resource object for a cluster's clientset
+type ResourceObjectFactory func(client kubernetes.Interface) ResourceObject
+
+var (
+	resourceObjectFactoriesMu sync.RWMutex
+	resourceObjectFactories = make(map[ResourceObjectType]ResourceObjectFactory)
+)
+
+func init() {
+	/*
+	the built-in types go through the same registration as user types
+	 */
+	builtins := []struct {
+		resourceObjectType ResourceObjectType
+		factory ResourceObjectFactory
+	}{
+		{KubernetesDeployment, func(client kubernetes.Interface) ResourceObject { return app.NewDeploymentForClient(client) }},
+		{KubernetesService, func(client kubernetes.Interface) ResourceObject { return service.NewForClient(client) }},
+		{KubernetesJob, func(client kubernetes.Interface) ResourceObject { return batch.NewForClient(client) }},
+		{KubernetesConfigMap, func(client kubernetes.Interface) ResourceObject { return configmap.NewForClient(client) }},
+		{KubernetesEvent, func(client kubernetes.Interface) ResourceObject { return event.NewForClient(client) }},
+		{KubernetesPod, func(client kubernetes.Interface) ResourceObject { return pod.NewForClient(client) }},
+		{KubernetesSecret, func(client kubernetes.Interface) ResourceObject { return secret.NewForClient(client) }},
+	}
+	for _, b := range builtins {
+		if err := RegisterResourceObject(b.resourceObjectType, b.factory); err != nil {
+			panic(err)
+		}
+	}
 }
 
-func (k *k8sClient) register() (r ResourceObjectRegister) {
-	r[KubernetesDeployment] = app.NewDeploymentForClient(k.client)
-	r[KubernetesService] = service.NewForClient(k.client)
-	r[KubernetesJob] = batch.NewForClient(k.client)
-	r[KubernetesConfigMap] = configmap.NewForClient(k.client)
-	r[KubernetesEvent] = event.NewForClient(k.client)
-	r[KubernetesPod] = pod.NewForClient(k.client)
-	r[KubernetesSecret] = secret.NewForClient(k.client)
+// RegisterResourceObject plugs a resource object into CommonResourceObject for every registry.
+// It is meant to be called from init, registering a type twice is an error.
+// The object may implement SetClusterName, SetRetryPolicy and SetApplyOptions to receive the client's settings.
+func RegisterResourceObject(resourceObjectType ResourceObjectType, factory ResourceObjectFactory) (err error) {
+	if len(resourceObjectType) == 0 {
+		return fmt.Errorf("empty resource object type")
+	}
+	if factory == nil {
+		return fmt.Errorf("nil factory for resource object type %s", resourceObjectType)
+	}
+	resourceObjectFactoriesMu.Lock()
+	defer resourceObjectFactoriesMu.Unlock()
+	if _, ok := resourceObjectFactories[resourceObjectType]; ok {
+		return fmt.Errorf("resource object type %s is already registered", resourceObjectType)
+	}
+	resourceObjectFactories[resourceObjectType] = factory
+	return
+}
+
+// RegisteredResourceObjectTypes returns the registered types sorted by name
+func RegisteredResourceObjectTypes() (resourceObjectTypes []ResourceObjectType) {
+	resourceObjectFactoriesMu.RLock()
+	for t := range resourceObjectFactories {
+		resourceObjectTypes = append(resourceObjectTypes, t)
+	}
+	resourceObjectFactoriesMu.RUnlock()
+	sort.Slice(resourceObjectTypes, func(i, j int) bool {
+		return resourceObjectTypes[i] < resourceObjectTypes[j]
+	})
+	return
+}
+
+func resourceObjectFactory(resourceObjectType ResourceObjectType) (factory ResourceObjectFactory, ok bool) {
+	resourceObjectFactoriesMu.RLock()
+	factory, ok = resourceObjectFactories[resourceObjectType]
+	resourceObjectFactoriesMu.RUnlock()
 	return
 }
 
 func (k *k8sClient) CommonResourceObject(resourceObjectType ResourceObjectType) ResourceObject {
-	r := k.register()
-	o, ok := r[resourceObjectType]
+	factory, ok := resourceObjectFactory(resourceObjectType)
 	if !ok {
-		err := fmt.Errorf("invalid resourceObjectType %s", resourceObjectType)
-		o.SetErr(err)
+		if len(resourceObjectType) == 0 {
+			return &invalidResourceObject{
+				err: k8serrors.UnknownResourceType(k.clusterName, resourceObjectType.String()),
+			}
+		}
+		if c, err := k.cluster(); err == nil && c.dynamic == nil {
+			// a cluster loaded from a bare clientset only knows the built-in types
+			_, _, err = k.dynamicClient()
+			return &invalidResourceObject{
+				err: &k8serrors.Error{
+					Kind: k8serrors.ErrUnknownResourceType,
+					Cluster: k.clusterName,
+					ResourceType: resourceObjectType.String(),
+					Err: fmt.Errorf("%v, %w", k8serrors.ErrUnknownResourceType, err),
+				},
+			}
+		}
+		// anything else is taken as a kind, e.g. Rollout.argoproj.io, and served by the dynamic client
+		return k.DynamicKind(resourceObjectType.String())
+	}
+	client, clientErr := k.clientset()
+	o := factory(client)
+	if o == nil {
+		return &invalidResourceObject{
+			err: k8serrors.UnknownResourceType(k.clusterName, resourceObjectType.String()),
+		}
+	}
+	if s, ok := o.(interface{ SetClusterName(clusterName string) }); ok {
+		s.SetClusterName(k.clusterName)
+	}
+	if s, ok := o.(interface{ SetRetryPolicy(policy *retry.Policy) }); ok {
+		s.SetRetryPolicy(k.clients.options.retryPolicy)
+	}
+	if s, ok := o.(interface{ SetApplyOptions(options *apply.Options) }); ok {
+		s.SetApplyOptions(k.clients.options.applyOptions)
+	}
+	if clientErr != nil {
+		o.SetErr(clientErr)
 	}
 	return o
 }
 
 func (k *k8sClient) Service() service.Service {
-	r := service.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := service.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) Pod() pod.Pod {
-	r := pod.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := pod.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) Namespace() namespace.Namespace {
-	r := namespace.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := namespace.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) Event() event.Event {
-	r := event.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := event.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) Deployment() app.Deployment {
-	r := app.NewDeploymentForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := app.NewDeploymentForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) ConfigMap() configmap.ConfigMap {
-	r := configmap.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := configmap.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) Secret() secret.Secret {
-	r := secret.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := secret.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
 
 func (k *k8sClient) Job() batch.Job {
-	r := batch.NewForClient(k.client)
-	if k.err != nil {
-		r.SetErr(k.err)
+	client, err := k.clientset()
+	r := batch.NewForClient(client)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
+	}
+	return r
+}
+
+// Dynamic works on any kind through the dynamic client, an empty version picks the preferred one
+func (k *k8sClient) Dynamic(gvk schema.GroupVersionKind) dynamic.Resource {
+	client, mapper, err := k.dynamicClient()
+	r := dynamic.NewForGVK(client, mapper, gvk)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
 	}
 	return r
 }
+
+// DynamicKind is Dynamic addressed the way kubectl does: Kind, Kind.group or Kind.version.group
+func (k *k8sClient) DynamicKind(kind string) dynamic.Resource {
+	client, mapper, err := k.dynamicClient()
+	r := dynamic.NewForKind(client, mapper, kind)
+	r.SetClusterName(k.clusterName)
+	r.SetRetryPolicy(k.clients.options.retryPolicy)
+	r.SetApplyOptions(k.clients.options.applyOptions)
+	if err != nil {
+		r.SetErr(err)
+	}
+	return r
+}
+
+// invalidResourceObject is returned for unknown resource types so the error surfaces on first use
+type invalidResourceObject struct {
+	err 					error
+}
+
+func (o *invalidResourceObject) SetErr(err error) {
+	o.err = err
+}
+
+func (o *invalidResourceObject) Create(input string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) Delete(namespace string, name string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) Update(input string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) Apply(input string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) Get(namespace string, name string) (ret string, err error) {
+	return "", o.err
+}
+
+func (o *invalidResourceObject) CreateContext(ctx context.Context, input string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) UpdateContext(ctx context.Context, input string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) ApplyContext(ctx context.Context, input string) (err error) {
+	return o.err
+}
+
+func (o *invalidResourceObject) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
+	return "", o.err
+}
+
+func (o *invalidResourceObject) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return "", o.err
+}
+
+func (o *invalidResourceObject) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return "", o.err
+}
+
+func (o *invalidResourceObject) List(namespace string, options ListOptions) (page *ListPage, err error) {
+	return nil, o.err
+}
+
+func (o *invalidResourceObject) ListContext(ctx context.Context, namespace string, options ListOptions) (page *ListPage, err error) {
+	return nil, o.err
+}
+
+func (o *invalidResourceObject) Iterate(namespace string, options ListOptions) *list.Iterator {
+	return o.IterateContext(context.Background(), namespace, options)
+}
+
+func (o *invalidResourceObject) IterateContext(ctx context.Context, namespace string, options ListOptions) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return nil, o.err
+	})
+}
+
+func (o *invalidResourceObject) Watch(ctx context.Context, namespace string, options WatchOptions) (events <-chan WatchEvent, err error) {
+	return nil, o.err
+}
@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+current-context: %[1]s
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: %[1]s-token
+`
+
+// writeKubeConfigs writes one kubeconfig file per context name into a temp dir, keyed context name -> server
+func writeKubeConfigs(t *testing.T, servers map[string]string) (dir string, paths []string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, server := range servers {
+		path := filepath.Join(dir, strings.Replace(name, "/", "_", -1)+".yaml")
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(testKubeConfig, name, server)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return
+}
+
+func TestNewFromKubeconfigContexts(t *testing.T) {
+	eksProd := "arn:aws:eks:eu-west-1:123:cluster/prod"
+	dir, paths := writeKubeConfigs(t, map[string]string{
+		eksProd: "https://prod:6443",
+		"kind-test": "https://127.0.0.1:6443",
+	})
+	defer os.RemoveAll(dir)
+	clients, err := NewFromKubeconfigContexts(paths, &ContextFilter{Include: []string{"*prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Close()
+	if names := clients.List(); !reflect.DeepEqual(names, []string{eksProd}) {
+		t.Fatalf("clusters = %v, want [%s]", names, eksProd)
+	}
+	metadata, _ := clients.Metadata(eksProd)
+	if metadata.Context != eksProd || metadata.Server != "https://prod:6443" {
+		t.Errorf("metadata = %+v, want context %s on https://prod:6443", metadata, eksProd)
+	}
+	if _, err := NewFromKubeconfigContexts(paths, &ContextFilter{Include: []string{"minikube"}}); err == nil {
+		t.Error("no matching context: err = nil")
+	}
+}
+
+func TestNewFromKubeconfigEnv(t *testing.T) {
+	dir, paths := writeKubeConfigs(t, map[string]string{
+		"prod": "https://prod:6443",
+		"test": "https://test:6443",
+	})
+	defer os.RemoveAll(dir)
+	old, set := os.LookupEnv("KUBECONFIG")
+	defer func() {
+		if set {
+			os.Setenv("KUBECONFIG", old)
+		} else {
+			os.Unsetenv("KUBECONFIG")
+		}
+	}()
+	os.Setenv("KUBECONFIG", strings.Join(paths, string(filepath.ListSeparator)))
+	clients, err := NewFromKubeconfigEnv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Close()
+	if names := clients.List(); !reflect.DeepEqual(names, []string{"prod", "test"}) {
+		t.Errorf("clusters = %v, want [prod test]", names)
+	}
+}
+
+func TestNewFromKubeconfigData(t *testing.T) {
+	const envName = "TEST_KUBECONFIG_STAGING"
+	old, set := os.LookupEnv(envName)
+	defer func() {
+		if set {
+			os.Setenv(envName, old)
+		} else {
+			os.Unsetenv(envName)
+		}
+	}()
+	os.Setenv(envName, base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(testKubeConfig, "staging", "https://staging:6443"))))
+	tests := []struct {
+		build 				func() (K8SClients, error)
+		wantSource 			string
+	}{
+		{func() (K8SClients, error) {
+			return NewFromKubeconfigData([]KubeConfigData{{ClusterName: "prod", Data: []byte(fmt.Sprintf(testKubeConfig, "prod", "https://prod:6443"))}})
+		}, "data"},
+		{func() (K8SClients, error) {
+			encoded := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(testKubeConfig, "prod", "https://prod:6443")))
+			return NewFromKubeconfigBase64([]KubeConfigBase64{{ClusterName: "prod", Data: encoded}})
+		}, "base64"},
+		{func() (K8SClients, error) {
+			return NewFromKubeconfigEnvVars([]KubeConfigEnv{{ClusterName: "prod", EnvName: envName}})
+		}, "env:" + envName},
+	}
+	for _, test := range tests {
+		clients, err := test.build()
+		if err != nil {
+			t.Errorf("%s: %v", test.wantSource, err)
+			continue
+		}
+		metadata, ok := clients.Metadata("prod")
+		if !ok || metadata.Source != test.wantSource {
+			t.Errorf("%s: metadata = %+v, want prod loaded from %s", test.wantSource, metadata, test.wantSource)
+		}
+		clients.Close()
+	}
+
+	_, err := NewFromKubeconfigData([]KubeConfigData{{ClusterName: "prod", Data: []byte("clusters: [")}})
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) || !strings.Contains(err.Error(), "prod") {
+		t.Errorf("broken data: err = %v, want ErrInvalidKubeConfig naming the cluster", err)
+	}
+	_, err = NewFromKubeconfigEnvVars([]KubeConfigEnv{{ClusterName: "prod", EnvName: "TEST_KUBECONFIG_UNSET"}})
+	if !errors.Is(err, k8serrors.ErrInvalidKubeConfig) {
+		t.Errorf("unset variable: err = %v, want ErrInvalidKubeConfig", err)
+	}
+}
+
+func TestAsUser(t *testing.T) {
+	var requests []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"default"}}`)
+	}))
+	defer server.Close()
+	clients, err := NewFromKubeconfigs([]RestKubeConfig{{
+		ClusterName: "prod",
+		KubeConfig: &rest.Config{Host: server.URL, BearerToken: "admin-token"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Close()
+
+	client := clients.GetClient("prod")
+	developer := client.AsUser("alice", []string{"dev", "oncall"}, map[string][]string{"scopes": {"view"}})
+	if _, err := developer.ConfigMap().Get("default", "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ConfigMap().Get("default", "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	impersonated, plain := requests[0], requests[1]
+	// the impersonating client still authenticates with the cluster's own credentials
+	if impersonated.Get("Authorization") != "Bearer admin-token" {
+		t.Errorf("Authorization = %q, want the cluster's token", impersonated.Get("Authorization"))
+	}
+	if impersonated.Get("Impersonate-User") != "alice" ||
+		!reflect.DeepEqual(impersonated["Impersonate-Group"], []string{"dev", "oncall"}) ||
+		impersonated.Get("Impersonate-Extra-Scopes") != "view" {
+		t.Errorf("headers = %v, want alice in dev and oncall with scopes=view", impersonated)
+	}
+	if len(plain.Get("Impersonate-User")) != 0 {
+		t.Errorf("headers = %v, want the original client left alone", plain)
+	}
+
+	if err := client.AsUser("", nil, nil).ConfigMap().Create(""); err == nil {
+		t.Error("AsUser without a user name: err = nil")
+	}
+	clients.Load("fake", fake.NewSimpleClientset())
+	if _, err := clients.GetClient("fake").AsUser("alice", nil, nil).ConfigMap().Get("default", "settings"); err == nil {
+		t.Error("AsUser on a cluster loaded without a rest config: err = nil")
+	}
+}
@@ -0,0 +1,115 @@
+package list
+
+import (
+	"context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultPageSize is the page size of an Iterator whose options set no Limit
+const DefaultPageSize = 500
+
+type Options struct {
+	// LabelSelector filters by labels, e.g. "app=nginx,tier!=cache"
+	LabelSelector 			string
+	// FieldSelector filters by fields, e.g. "status.phase=Running"
+	FieldSelector 			string
+	// AllNamespaces lists every namespace, the namespace argument is then ignored
+	AllNamespaces 			bool
+	// Limit is the page size, 0 lists everything in one request
+	Limit 					int64
+	// Continue is Page.Continue of the previous page
+	Continue 				string
+}
+
+// Page is one page of a list, Items holds each object as JSON like Get returns it
+type Page struct {
+	Items 					[]string
+	// Continue fetches the next page when set as Options.Continue, empty on the last page
+	Continue 				string
+	ResourceVersion 		string
+	// RemainingItemCount is the server's estimate of the items after this page, nil when unknown
+	RemainingItemCount 		*int64
+}
+
+// FetchFunc lists one page, the resource packages pass their ListContext
+type FetchFunc func(ctx context.Context, options Options) (page *Page, err error)
+
+// Iterator walks a list page by page, only one page is held in memory at a time
+type Iterator struct {
+	ctx 					context.Context
+	options 				Options
+	fetch 					FetchFunc
+	items 					[]string
+	item 					string
+	done 					bool
+	err 					error
+}
+
+// Namespace returns the namespace to list, empty for all namespaces
+func (o Options) Namespace(namespace string) string {
+	if o.AllNamespaces {
+		return metav1.NamespaceAll
+	}
+	return namespace
+}
+
+func (o Options) ListOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: o.LabelSelector,
+		FieldSelector: o.FieldSelector,
+		Limit: o.Limit,
+		Continue: o.Continue,
+	}
+}
+
+// NewPage returns an empty page carrying the list's paging metadata
+func NewPage(list metav1.ListInterface) *Page {
+	return &Page{
+		Continue: list.GetContinue(),
+		ResourceVersion: list.GetResourceVersion(),
+		RemainingItemCount: list.GetRemainingItemCount(),
+	}
+}
+
+func NewIterator(ctx context.Context, options Options, fetch FetchFunc) *Iterator {
+	if options.Limit <= 0 {
+		options.Limit = DefaultPageSize
+	}
+	return &Iterator{
+		ctx: ctx,
+		options: options,
+		fetch: fetch,
+	}
+}
+
+// Next advances to the next item, fetching the next page when the current one is used up.
+// It returns false at the end of the list or on an error, see Err.
+func (it *Iterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		page, err := it.fetch(it.ctx, it.options)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = page.Items
+		it.options.Continue = page.Continue
+		it.done = len(page.Continue) == 0
+	}
+	it.item = it.items[0]
+	it.items = it.items[1:]
+	return true
+}
+
+// Item returns the current object as JSON
+func (it *Iterator) Item() string {
+	return it.item
+}
+
+// Err returns the error that stopped Next. A continue token that expired while iterating
+// slowly surfaces here as ErrExpired, start a new iterator to list again.
+func (it *Iterator) Err() error {
+	return it.err
+}
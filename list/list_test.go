@@ -0,0 +1,119 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOptions(t *testing.T) {
+	options := Options{
+		LabelSelector: "app=nginx",
+		FieldSelector: "status.phase=Running",
+		Limit: 10,
+		Continue: "token",
+	}
+	want := metav1.ListOptions{
+		LabelSelector: "app=nginx",
+		FieldSelector: "status.phase=Running",
+		Limit: 10,
+		Continue: "token",
+	}
+	if got := options.ListOptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListOptions() = %+v, want %+v", got, want)
+	}
+	if namespace := options.Namespace("default"); namespace != "default" {
+		t.Errorf("Namespace = %q, want default", namespace)
+	}
+	options.AllNamespaces = true
+	if namespace := options.Namespace("default"); namespace != metav1.NamespaceAll {
+		t.Errorf("Namespace with AllNamespaces = %q, want all namespaces", namespace)
+	}
+}
+
+// pagedFetch serves items in pages of options.Limit, the continue token is the offset
+func pagedFetch(items []string, calls *[]Options) FetchFunc {
+	return func(ctx context.Context, options Options) (*Page, error) {
+		*calls = append(*calls, options)
+		offset := 0
+		if len(options.Continue) != 0 {
+			fmt.Sscanf(options.Continue, "%d", &offset)
+		}
+		end := offset + int(options.Limit)
+		page := &Page{}
+		if end < len(items) {
+			page.Continue = fmt.Sprint(end)
+		} else {
+			end = len(items)
+		}
+		page.Items = items[offset:end]
+		return page, nil
+	}
+}
+
+func TestIterator(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	var calls []Options
+	it := NewIterator(context.Background(), Options{Limit: 2, LabelSelector: "app=nginx"}, pagedFetch(items, &calls))
+	var got []string
+	for it.Next() {
+		got = append(got, it.Item())
+	}
+	if it.Err() != nil || !reflect.DeepEqual(got, items) {
+		t.Errorf("items = %v, err = %v, want %v", got, it.Err(), items)
+	}
+	if len(calls) != 3 || calls[1].Continue != "2" || calls[2].LabelSelector != "app=nginx" {
+		t.Errorf("fetches = %+v, want 3 pages carrying the continue token and the selector", calls)
+	}
+	if it.Next() {
+		t.Error("Next after the end = true")
+	}
+
+	calls = nil
+	it = NewIterator(context.Background(), Options{}, pagedFetch(items, &calls))
+	for it.Next() {
+	}
+	if len(calls) != 1 || calls[0].Limit != DefaultPageSize {
+		t.Errorf("fetches = %+v, want one page of DefaultPageSize", calls)
+	}
+}
+
+func TestIteratorError(t *testing.T) {
+	expired := errors.New("continue token expired")
+	pages := 0
+	it := NewIterator(context.Background(), Options{Limit: 1}, func(ctx context.Context, options Options) (*Page, error) {
+		pages++
+		if pages == 2 {
+			return nil, expired
+		}
+		return &Page{Items: []string{"a"}, Continue: "next"}, nil
+	})
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != 1 || it.Err() != expired {
+		t.Errorf("%d items, err = %v, want 1 item and the fetch error", n, it.Err())
+	}
+	if it.Next() || pages != 2 {
+		t.Errorf("Next after an error fetched again, %d fetches", pages)
+	}
+}
+
+func TestIteratorSkipsEmptyPages(t *testing.T) {
+	pages := []*Page{
+		{Items: nil, Continue: "1"},
+		{Items: []string{"a"}, Continue: ""},
+	}
+	it := NewIterator(context.Background(), Options{}, func(ctx context.Context, options Options) (*Page, error) {
+		page := pages[0]
+		pages = pages[1:]
+		return page, nil
+	})
+	if !it.Next() || it.Item() != "a" || it.Next() {
+		t.Error("want the item after an empty page and nothing more")
+	}
+}
@@ -0,0 +1,205 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+type LogEntry struct {
+	Cluster 				string
+	Verb 					string
+	Resource 				string
+	Subresource 			string
+	Namespace 				string
+	Name 					string
+	Duration 				time.Duration
+	StatusCode 				int
+	// Err is set when the request failed or the API server answered with an error status
+	Err 					error
+	// RequestBody and ResponseBody are only filled in verbose mode, secret values are redacted
+	RequestBody 			string
+	ResponseBody 			string
+}
+
+type Logger interface {
+	Log(entry LogEntry)
+}
+
+type LoggerFunc func(entry LogEntry)
+
+func (f LoggerFunc) Log(entry LogEntry) {
+	f(entry)
+}
+
+type stdLogger struct {
+	logger 					*log.Logger
+}
+
+// NewStdLogger writes one line per request to logger
+func NewStdLogger(logger *log.Logger) Logger {
+	return &stdLogger{
+		logger: logger,
+	}
+}
+
+func (l *stdLogger) Log(e LogEntry) {
+	object := e.Name
+	if len(e.Namespace) != 0 {
+		object = e.Namespace + "/" + e.Name
+	}
+	resource := e.Resource
+	if len(e.Subresource) != 0 {
+		resource += "/" + e.Subresource
+	}
+	outcome := "ok"
+	if e.Err != nil {
+		outcome = e.Err.Error()
+	}
+	l.logger.Printf("cluster=%s verb=%s resource=%s object=%s status=%d duration=%s outcome=%q",
+		e.Cluster, e.Verb, resource, object, e.StatusCode, e.Duration, outcome)
+	if len(e.RequestBody) != 0 {
+		l.logger.Printf("cluster=%s request body: %s", e.Cluster, e.RequestBody)
+	}
+	if len(e.ResponseBody) != 0 {
+		l.logger.Printf("cluster=%s response body: %s", e.Cluster, e.ResponseBody)
+	}
+}
+
+// WithLogger records every API request with cluster, verb, resource, namespace, name, duration and outcome
+func WithLogger(logger Logger) Option {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithVerboseLogging also logs request and response bodies, with the values of secrets redacted
+func WithVerboseLogging() Option {
+	return func(o *clientOptions) {
+		o.verbose = true
+	}
+}
+
+type loggingRoundTripper struct {
+	cluster 				string
+	logger 					Logger
+	verbose 				bool
+	delegate 				http.RoundTripper
+}
+
+func (rt *loggingRoundTripper) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	info := parseRequestInfo(req)
+	entry := LogEntry{
+		Cluster: rt.cluster,
+		Verb: info.verb,
+		Resource: info.resource,
+		Subresource: info.subresource,
+		Namespace: info.namespace,
+		Name: info.name,
+	}
+	if rt.verbose && req.GetBody != nil {
+		if body, bodyErr := req.GetBody(); bodyErr == nil {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			entry.RequestBody = redactBody(data, info.resource)
+		}
+	}
+	start := time.Now()
+	resp, err = rt.delegate.RoundTrip(req)
+	entry.Duration = time.Since(start)
+	switch {
+	case err != nil:
+		entry.Err = err
+	default:
+		entry.StatusCode = resp.StatusCode
+		if resp.StatusCode >= http.StatusBadRequest {
+			entry.Err = fmt.Errorf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		if rt.verbose && !isStreaming(req, info) {
+			data, readErr := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+			if readErr == nil {
+				entry.ResponseBody = redactBody(data, info.resource)
+			}
+		}
+	}
+	rt.logger.Log(entry)
+	return
+}
+
+// redactBody hides the data of Secrets (and of Secrets inside lists) from logged JSON bodies.
+// Bodies sent to or from the secrets resource are never logged raw, patches there carry no kind.
+func redactBody(data []byte, resource string) string {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		if resource == "secrets" {
+			// JSON patch arrays or protobuf, nothing we can redact field by field
+			return redacted
+		}
+		// not JSON (e.g. pod logs), nothing we know how to redact
+		return string(data)
+	}
+	kind, _ := object["kind"].(string)
+	if resource == "secrets" {
+		kind = "Secret"
+		if _, ok := object["items"]; ok {
+			kind = "SecretList"
+		}
+	}
+	if !redactObject(object, kind) {
+		return string(data)
+	}
+	redactedData, err := json.Marshal(object)
+	if err != nil {
+		return redacted
+	}
+	return string(redactedData)
+}
+
+func redactObject(object map[string]interface{}, kind string) (changed bool) {
+	if kind == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			if values, ok := object[field].(map[string]interface{}); ok {
+				for k := range values {
+					values[k] = redacted
+				}
+				changed = true
+			}
+		}
+		// kubectl keeps the whole applied object, data included, in this annotation
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+				if _, ok := annotations["kubectl.kubernetes.io/last-applied-configuration"]; ok {
+					annotations["kubectl.kubernetes.io/last-applied-configuration"] = redacted
+					changed = true
+				}
+			}
+		}
+	}
+	if strings.HasSuffix(kind, "List") {
+		items, _ := object["items"].([]interface{})
+		for _, item := range items {
+			itemObject, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// items of typed lists carry no kind, the list kind tells what they are
+			itemKind, ok := itemObject["kind"].(string)
+			if !ok {
+				itemKind = strings.TrimSuffix(kind, "List")
+			}
+			if redactObject(itemObject, itemKind) {
+				changed = true
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,168 @@
+package k8s
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// respondWith answers every request with status and body
+func respondWith(status int, body string) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Body: ioutil.NopCloser(strings.NewReader(body)),
+			Request: req,
+		}, nil
+	})
+}
+
+func TestParseRequestInfo(t *testing.T) {
+	tests := []struct {
+		method 				string
+		url 				string
+		want 				requestInfo
+	}{
+		{http.MethodGet, "/apis/apps/v1/namespaces/default/deployments/nginx", requestInfo{"get", "deployments", "", "default", "nginx"}},
+		{http.MethodGet, "/api/v1/namespaces/default/pods", requestInfo{"list", "pods", "", "default", ""}},
+		{http.MethodGet, "/api/v1/pods?watch=true", requestInfo{"watch", "pods", "", "", ""}},
+		{http.MethodGet, "/api/v1/namespaces/default/pods/web/log", requestInfo{"get", "pods", "log", "default", "web"}},
+		{http.MethodPost, "/api/v1/namespaces/default/configmaps", requestInfo{"create", "configmaps", "", "default", ""}},
+		{http.MethodPut, "/apis/apps/v1/namespaces/default/deployments/nginx/status", requestInfo{"update", "deployments", "status", "default", "nginx"}},
+		{http.MethodPatch, "/api/v1/namespaces/kube-system", requestInfo{"patch", "namespaces", "", "", "kube-system"}},
+		{http.MethodPut, "/api/v1/namespaces/kube-system/finalize", requestInfo{"update", "namespaces", "finalize", "", "kube-system"}},
+		{http.MethodDelete, "/api/v1/namespaces/default/secrets", requestInfo{"deletecollection", "secrets", "", "default", ""}},
+		{http.MethodDelete, "/api/v1/nodes/node-1", requestInfo{"delete", "nodes", "", "", "node-1"}},
+		{http.MethodGet, "/version", requestInfo{"get", "", "", "", "/version"}},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, "https://10.0.0.1:6443"+test.url, nil)
+		if info := parseRequestInfo(req); info != test.want {
+			t.Errorf("%s %s = %+v, want %+v", test.method, test.url, info, test.want)
+		}
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name 				string
+		body 				string
+		resource 			string
+		hidden 				[]string
+		kept 				[]string
+	}{
+		{
+			"secret",
+			`{"kind":"Secret","metadata":{"name":"db","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"password\":\"aHVudGVyMg==\"}}"}},"data":{"password":"aHVudGVyMg=="},"stringData":{"user":"admin"}}`,
+			"secrets",
+			[]string{"aHVudGVyMg==", "admin"},
+			[]string{`"name":"db"`, `"password":"REDACTED"`},
+		},
+		{
+			"typed secret list",
+			`{"kind":"SecretList","items":[{"metadata":{"name":"db"},"data":{"password":"aHVudGVyMg=="}}]}`,
+			"secrets",
+			[]string{"aHVudGVyMg=="},
+			[]string{`"name":"db"`},
+		},
+		{
+			"secret inside a generic list",
+			`{"kind":"List","items":[{"kind":"ConfigMap","data":{"level":"info"}},{"kind":"Secret","data":{"token":"c2VjcmV0"}}]}`,
+			"",
+			[]string{"c2VjcmV0"},
+			[]string{`"level":"info"`},
+		},
+		{
+			"merge patch without a kind",
+			`{"data":{"password":"bmV3"}}`,
+			"secrets",
+			[]string{"bmV3"},
+			nil,
+		},
+		{
+			"json patch",
+			`[{"op":"replace","path":"/data/password","value":"bmV3"}]`,
+			"secrets",
+			[]string{"bmV3"},
+			nil,
+		},
+		{
+			"configmap",
+			`{"kind":"ConfigMap","data":{"level":"info"}}`,
+			"configmaps",
+			nil,
+			[]string{`"level":"info"`},
+		},
+		{
+			"pod logs",
+			"starting server on :8080",
+			"pods",
+			nil,
+			[]string{"starting server on :8080"},
+		},
+	}
+	for _, test := range tests {
+		got := redactBody([]byte(test.body), test.resource)
+		for _, s := range test.hidden {
+			if strings.Contains(got, s) {
+				t.Errorf("%s: %s leaks %s", test.name, got, s)
+			}
+		}
+		for _, s := range test.kept {
+			if !strings.Contains(got, s) {
+				t.Errorf("%s: %s lost %s", test.name, got, s)
+			}
+		}
+	}
+}
+
+func TestLoggingRoundTripper(t *testing.T) {
+	var entries []LogEntry
+	rt := &loggingRoundTripper{
+		cluster: "prod",
+		logger: LoggerFunc(func(entry LogEntry) {
+			entries = append(entries, entry)
+		}),
+		verbose: true,
+		delegate: respondWith(http.StatusConflict, `{"kind":"Secret","data":{"password":"c2VjcmV0"}}`),
+	}
+	body := `{"kind":"Secret","metadata":{"name":"db"},"data":{"password":"aHVudGVyMg=="}}`
+	req, _ := http.NewRequest(http.MethodPut, "https://10.0.0.1:6443/api/v1/namespaces/default/secrets/db", bytes.NewReader([]byte(body)))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the body was read for the log, the caller must still get all of it
+	if data, _ := ioutil.ReadAll(resp.Body); !strings.Contains(string(data), "c2VjcmV0") {
+		t.Errorf("response body = %s, want it unchanged for the caller", data)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d log entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Cluster != "prod" || e.Verb != "update" || e.Resource != "secrets" || e.Namespace != "default" || e.Name != "db" {
+		t.Errorf("entry = %+v, want an update of secrets default/db on prod", e)
+	}
+	if e.StatusCode != http.StatusConflict || e.Err == nil {
+		t.Errorf("entry status = %d, err = %v, want 409 reported as error", e.StatusCode, e.Err)
+	}
+	if strings.Contains(e.RequestBody, "aHVudGVyMg==") || strings.Contains(e.ResponseBody, "c2VjcmV0") || len(e.RequestBody) == 0 {
+		t.Errorf("bodies = %s / %s, want them logged with the secret values redacted", e.RequestBody, e.ResponseBody)
+	}
+
+	rt.verbose = false
+	entries = nil
+	req, _ = http.NewRequest(http.MethodGet, "https://10.0.0.1:6443/api/v1/namespaces/default/secrets/db", nil)
+	rt.RoundTrip(req)
+	if len(entries) != 1 || len(entries[0].RequestBody) != 0 || len(entries[0].ResponseBody) != 0 {
+		t.Errorf("entries = %+v, want one entry without bodies", entries)
+	}
+}
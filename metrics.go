@@ -0,0 +1,179 @@
+package k8s
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histogram (same as Prometheus' defaults)
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// resourceObjectTypes maps API resource names to the library's resource object types
+var resourceObjectTypes = map[string]ResourceObjectType{
+	"deployments": KubernetesDeployment,
+	"services": KubernetesService,
+	"jobs": KubernetesJob,
+	"configmaps": KubernetesConfigMap,
+	"events": KubernetesEvent,
+	"pods": KubernetesPod,
+	"secrets": KubernetesSecret,
+}
+
+type RequestMetric struct {
+	Cluster 				string
+	ResourceType 			ResourceObjectType
+	Verb 					string
+	StatusCode 				int
+	Duration 				time.Duration
+	// Err is set when the request failed or the API server answered with an error status
+	Err 					error
+}
+
+// Metrics receives one observation per API request
+type Metrics interface {
+	Observe(metric RequestMetric)
+}
+
+// WithMetrics reports every API request, labeled by cluster, resource type and verb
+func WithMetrics(metrics Metrics) Option {
+	return func(o *clientOptions) {
+		o.metrics = metrics
+	}
+}
+
+type MetricKey struct {
+	Cluster 				string
+	ResourceType 			ResourceObjectType
+	Verb 					string
+}
+
+type MetricValue struct {
+	Requests 				uint64
+	Errors 					uint64
+	LatencySum 				time.Duration
+	// LatencyBuckets holds cumulative counts for DefaultLatencyBuckets, same order
+	LatencyBuckets 			[]uint64
+}
+
+type MetricSample struct {
+	MetricKey
+	MetricValue
+}
+
+// MetricsRecorder is an in-memory Metrics, handy for tests and as the source of exporters
+type MetricsRecorder struct {
+	mu 						sync.Mutex
+	values 					map[MetricKey]*MetricValue
+}
+
+func NewMetricsRecorder() *MetricsRecorder {
+	return &MetricsRecorder{
+		values: make(map[MetricKey]*MetricValue),
+	}
+}
+
+func (r *MetricsRecorder) Observe(metric RequestMetric) {
+	key := MetricKey{
+		Cluster: metric.Cluster,
+		ResourceType: metric.ResourceType,
+		Verb: metric.Verb,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		value = &MetricValue{
+			LatencyBuckets: make([]uint64, len(DefaultLatencyBuckets)),
+		}
+		r.values[key] = value
+	}
+	value.Requests++
+	if metric.Err != nil {
+		value.Errors++
+	}
+	value.LatencySum += metric.Duration
+	seconds := metric.Duration.Seconds()
+	for i, upper := range DefaultLatencyBuckets {
+		if seconds <= upper {
+			value.LatencyBuckets[i]++
+		}
+	}
+}
+
+// Snapshot returns a copy of every series, sorted by cluster, resource type and verb
+func (r *MetricsRecorder) Snapshot() (samples []MetricSample) {
+	r.mu.Lock()
+	for key, value := range r.values {
+		v := *value
+		v.LatencyBuckets = append([]uint64(nil), value.LatencyBuckets...)
+		samples = append(samples, MetricSample{
+			MetricKey: key,
+			MetricValue: v,
+		})
+	}
+	r.mu.Unlock()
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i].MetricKey, samples[j].MetricKey
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType < b.ResourceType
+		}
+		return a.Verb < b.Verb
+	})
+	return
+}
+
+func (r *MetricsRecorder) Get(cluster string, resourceType ResourceObjectType, verb string) (value MetricValue, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.values[MetricKey{Cluster: cluster, ResourceType: resourceType, Verb: verb}]
+	if !ok {
+		return
+	}
+	value = *v
+	value.LatencyBuckets = append([]uint64(nil), v.LatencyBuckets...)
+	return value, true
+}
+
+func (r *MetricsRecorder) Reset() {
+	r.mu.Lock()
+	r.values = make(map[MetricKey]*MetricValue)
+	r.mu.Unlock()
+}
+
+type metricsRoundTripper struct {
+	cluster 				string
+	metrics 				Metrics
+	delegate 				http.RoundTripper
+}
+
+func (rt *metricsRoundTripper) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	info := parseRequestInfo(req)
+	resourceType, ok := resourceObjectTypes[info.resource]
+	if !ok {
+		resourceType = ResourceObjectType(info.resource)
+	}
+	metric := RequestMetric{
+		Cluster: rt.cluster,
+		ResourceType: resourceType,
+		Verb: info.verb,
+	}
+	start := time.Now()
+	resp, err = rt.delegate.RoundTrip(req)
+	metric.Duration = time.Since(start)
+	if err != nil {
+		metric.Err = err
+	} else {
+		metric.StatusCode = resp.StatusCode
+		if resp.StatusCode >= http.StatusBadRequest {
+			metric.Err = fmt.Errorf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+	}
+	rt.metrics.Observe(metric)
+	return
+}
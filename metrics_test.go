@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMetricsRecorder(t *testing.T) {
+	r := NewMetricsRecorder()
+	r.Observe(RequestMetric{Cluster: "prod", ResourceType: KubernetesDeployment, Verb: "get", StatusCode: 200, Duration: 20 * time.Millisecond})
+	r.Observe(RequestMetric{Cluster: "prod", ResourceType: KubernetesDeployment, Verb: "get", StatusCode: 503, Duration: 2 * time.Second, Err: errors.New("503 Service Unavailable")})
+	r.Observe(RequestMetric{Cluster: "dev", ResourceType: KubernetesPod, Verb: "list", StatusCode: 200, Duration: time.Millisecond})
+	value, ok := r.Get("prod", KubernetesDeployment, "get")
+	if !ok {
+		t.Fatal("no series for prod deployments get")
+	}
+	if value.Requests != 2 || value.Errors != 1 || value.LatencySum != 2020*time.Millisecond {
+		t.Errorf("value = %+v, want 2 requests, 1 error and 2.02s in total", value)
+	}
+	// buckets are cumulative: 20ms falls in 0.025 and up, 2s in 2.5 and up
+	for i, upper := range DefaultLatencyBuckets {
+		want := uint64(0)
+		if upper >= 0.025 {
+			want++
+		}
+		if upper >= 2.5 {
+			want++
+		}
+		if value.LatencyBuckets[i] != want {
+			t.Errorf("bucket le=%v = %d, want %d", upper, value.LatencyBuckets[i], want)
+		}
+	}
+	samples := r.Snapshot()
+	if len(samples) != 2 || samples[0].Cluster != "dev" || samples[1].Cluster != "prod" {
+		t.Errorf("snapshot = %+v, want dev then prod", samples)
+	}
+	// the snapshot is a copy
+	samples[1].LatencyBuckets[0] = 100
+	if value, _ := r.Get("prod", KubernetesDeployment, "get"); value.LatencyBuckets[0] != 0 {
+		t.Error("changing the snapshot changed the recorder")
+	}
+	r.Reset()
+	if _, ok := r.Get("prod", KubernetesDeployment, "get"); ok {
+		t.Error("series left after Reset")
+	}
+}
+
+func TestMetricsRoundTripper(t *testing.T) {
+	r := NewMetricsRecorder()
+	rt := &metricsRoundTripper{
+		cluster: "prod",
+		metrics: r,
+		delegate: respondWith(http.StatusNotFound, `{"kind":"Status"}`),
+	}
+	requests := []string{
+		"/apis/apps/v1/namespaces/default/deployments/nginx",
+		"/apis/argoproj.io/v1alpha1/namespaces/default/rollouts/web",
+	}
+	for _, url := range requests {
+		req, _ := http.NewRequest(http.MethodGet, "https://10.0.0.1:6443"+url, nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	value, ok := r.Get("prod", KubernetesDeployment, "get")
+	if !ok || value.Requests != 1 || value.Errors != 1 {
+		t.Errorf("deployments = %+v, want one failed get labeled with the deployment type", value)
+	}
+	// resources without a resource object type keep their API name
+	if _, ok := r.Get("prod", ResourceObjectType("rollouts"), "get"); !ok {
+		t.Errorf("series = %+v, want one for rollouts", r.Snapshot())
+	}
+
+	rt.delegate = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	req, _ := http.NewRequest(http.MethodPost, "https://10.0.0.1:6443/api/v1/namespaces/default/configmaps", nil)
+	rt.RoundTrip(req)
+	if value, _ := r.Get("prod", KubernetesConfigMap, "create"); value.Errors != 1 {
+		t.Errorf("configmaps create = %+v, want the connection error counted", value)
+	}
+}
@@ -1,13 +1,27 @@
 package namespace
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+	"encoding/json"
 )
 
+const resourceType = "namespace"
+
 type namespace struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
 	err 					error
 }
 
@@ -19,9 +33,22 @@ type Namespace interface {
 	Create(namespace string) (err error)
 	Delete(namespace string) (err error)
 	GetStatus(namespaceName string) (status string, err error)
+	// namespaces are cluster scoped, Patch only takes the namespace's own name
+	Patch(namespaceName string, patchType types.PatchType, input string) (ret string, err error)
+	// List and Iterate ignore options.AllNamespaces, every namespace is listed anyway
+	List(options list.Options) (page *list.Page, err error)
+	Iterate(options list.Options) *list.Iterator
+	CreateContext(ctx context.Context, namespace string) (err error)
+	DeleteContext(ctx context.Context, namespace string) (err error)
+	GetStatusContext(ctx context.Context, namespaceName string) (status string, err error)
+	PatchContext(ctx context.Context, namespaceName string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, options list.Options) *list.Iterator
+	// Watch ignores options.AllNamespaces like List
+	Watch(ctx context.Context, options watch.Options) (events <-chan watch.Event, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *namespace {
+func NewForClient(client kubernetes.Interface) *namespace {
 	return &namespace{
 		client: client,
 	}
@@ -31,7 +58,24 @@ func (c *namespace) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *namespace) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors, nil sends every request once
+func (c *namespace) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+func (c *namespace) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *namespace) Create(namespaceName string) (err error) {
+	return c.CreateContext(context.Background(), namespaceName)
+}
+
+func (c *namespace) CreateContext(ctx context.Context, namespaceName string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
@@ -40,32 +84,137 @@ func (c *namespace) Create(namespaceName string) (err error) {
 			Name: namespaceName,
 		},
 	}
-	_, err = c.client.CoreV1().Namespaces().Create(namespace)
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.client.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, "", namespaceName)
 	}
 	return
 }
 
 func (c *namespace) Delete(namespaceName string) (err error) {
+	return c.DeleteContext(context.Background(), namespaceName)
+}
+
+func (c *namespace) DeleteContext(ctx context.Context, namespaceName string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	err = c.client.CoreV1().Namespaces().Delete(namespaceName, &metav1.DeleteOptions{})
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.client.CoreV1().Namespaces().Delete(ctx, namespaceName, metav1.DeleteOptions{})
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, "", namespaceName)
 	}
 	return
 }
 
 func (c *namespace) GetStatus(namespaceName string) (status string, err error) {
+	return c.GetStatusContext(context.Background(), namespaceName)
+}
+
+func (c *namespace) GetStatusContext(ctx context.Context, namespaceName string) (status string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	namespace, err := c.client.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	var namespace *corev1.Namespace
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		namespace, err = c.client.CoreV1().Namespaces().Get(ctx, namespaceName, metav1.GetOptions{})
+		return
+	})
 	if err != nil {
+		err = c.wrapErr(err, "", namespaceName)
 		return
 	}
 	status = string(namespace.Status.Phase)
 	return
-}
\ No newline at end of file
+}
+
+func (c *namespace) Patch(namespaceName string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespaceName, patchType, input)
+}
+
+// PatchContext patches the namespace, e.g. its labels, with a YAML or JSON body and returns the patched object
+func (c *namespace) PatchContext(ctx context.Context, namespaceName string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var namespace *corev1.Namespace
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		namespace, err = c.client.CoreV1().Namespaces().Patch(ctx, namespaceName, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, "", namespaceName)
+	}
+	d, err := json.Marshal(namespace)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *namespace) List(options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), options)
+}
+
+// ListContext returns one page of namespaces, options.Limit and options.Continue page through the rest
+func (c *namespace) ListContext(ctx context.Context, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var namespaces *corev1.NamespaceList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		namespaces, err = c.client.CoreV1().Namespaces().List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, "", "")
+	}
+	page = list.NewPage(&namespaces.ListMeta)
+	for i := range namespaces.Items {
+		d, err := json.Marshal(&namespaces.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *namespace) Iterate(options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), options)
+}
+
+// IterateContext walks every page of namespaces lazily, one request per options.Limit items
+func (c *namespace) IterateContext(ctx context.Context, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, options)
+	})
+}
+
+// Watch streams changes to namespaces until ctx is done, reconnecting and resuming on its own
+func (c *namespace) Watch(ctx context.Context, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CoreV1().Namespaces().List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.CoreV1().Namespaces().Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
@@ -0,0 +1,139 @@
+package namespace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespaceCreateGetStatusDelete(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	c.SetClusterName("cluster1")
+	if err := c.Create("team-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("team-a"); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := c.GetStatus("team-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("team-a"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.GetStatus("team-a")
+	if !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("GetStatus after Delete: err = %v, want ErrNotFound", err)
+	}
+	var e *k8serrors.Error
+	if !errors.As(err, &e) || e.Cluster != "cluster1" || e.Name != "team-a" || len(e.Namespace) != 0 {
+		t.Errorf("GetStatus after Delete: err = %#v, want a *errors.Error for cluster1 namespace team-a", err)
+	}
+}
+
+func TestNamespaceGetStatus(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a",
+		},
+		Status: corev1.NamespaceStatus{
+			Phase: corev1.NamespaceTerminating,
+		},
+	}))
+	status, err := c.GetStatus("team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != string(corev1.NamespaceTerminating) {
+		t.Errorf("GetStatus = %s, want Terminating", status)
+	}
+}
+
+func TestNamespaceSetErr(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	want := errors.New("no client")
+	c.SetErr(want)
+	if err := c.Create("team-a"); err != want {
+		t.Errorf("Create: err = %v, want the error set with SetErr", err)
+	}
+}
+
+func TestNamespacePatch(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a",
+		},
+	}))
+	ret, err := c.Patch("team-a", types.MergePatchType, "metadata:\n  labels:\n    team: a\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespace := new(corev1.Namespace)
+	json.Unmarshal([]byte(ret), namespace)
+	if namespace.Labels["team"] != "a" {
+		t.Errorf("labels after Patch = %v, want team=a", namespace.Labels)
+	}
+	if _, err := c.Patch("team-b", types.MergePatchType, "{}"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Patch missing: err = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Patch("team-a", types.JSONPatchType, "{}"); !errors.Is(err, k8serrors.ErrInvalidManifest) {
+		t.Errorf("Patch invalid body: err = %v, want ErrInvalidManifest", err)
+	}
+}
+
+func TestNamespaceListAndIterate(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+	))
+	page, err := c.List(list.Options{LabelSelector: "team=a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("List(team=a) returned %d items, want 1", len(page.Items))
+	}
+	it := c.Iterate(list.Options{})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Iterate returned %d namespaces, want 3", count)
+	}
+}
+
+func TestNamespaceWatch(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a",
+		},
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := c.Watch(ctx, watch.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != watch.Added || event.Name != "team-a" {
+			t.Errorf("first event = %+v, want Added team-a", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event before the timeout")
+	}
+}
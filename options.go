@@ -0,0 +1,135 @@
+package k8s
+
+import (
+	"net/http"
+	"time"
+	k8sconfig "github.com/zhanghaohao/kubernetes-client/config"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+)
+
+// Option configures the clients built by the New* constructors.
+// Options apply to every cluster, WithClusterOptions adds overrides for a single cluster.
+type Option func(o *clientOptions)
+
+type clientOptions struct {
+	configOptions 			[]k8sconfig.Option
+	clusterOptions 			map[string][]k8sconfig.Option
+	refuseUnhealthy 		bool
+	logger 					Logger
+	verbose 				bool
+	metrics 				Metrics
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
+	clusterLabels 			map[string]map[string]string
+}
+
+func newClientOptions(opts []Option) *clientOptions {
+	o := &clientOptions{
+		clusterOptions: make(map[string][]k8sconfig.Option),
+		clusterLabels: make(map[string]map[string]string),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// forCluster returns the global options followed by the cluster's overrides, so overrides win
+func (o *clientOptions) forCluster(clusterName string) []k8sconfig.Option {
+	opts := make([]k8sconfig.Option, 0, len(o.configOptions)+len(o.clusterOptions[clusterName])+2)
+	opts = append(opts, o.configOptions...)
+	opts = append(opts, o.clusterOptions[clusterName]...)
+	if o.logger != nil {
+		opts = append(opts, k8sconfig.WithWrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &loggingRoundTripper{
+				cluster: clusterName,
+				logger: o.logger,
+				verbose: o.verbose,
+				delegate: rt,
+			}
+		}))
+	}
+	if o.metrics != nil {
+		opts = append(opts, k8sconfig.WithWrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &metricsRoundTripper{
+				cluster: clusterName,
+				metrics: o.metrics,
+				delegate: rt,
+			}
+		}))
+	}
+	return opts
+}
+
+func WithConfigOptions(opts ...k8sconfig.Option) Option {
+	return func(o *clientOptions) {
+		o.configOptions = append(o.configOptions, opts...)
+	}
+}
+
+func WithClusterOptions(clusterName string, opts ...k8sconfig.Option) Option {
+	return func(o *clientOptions) {
+		o.clusterOptions[clusterName] = append(o.clusterOptions[clusterName], opts...)
+	}
+}
+
+func WithQPS(qps float32) Option {
+	return WithConfigOptions(k8sconfig.WithQPS(qps))
+}
+
+func WithBurst(burst int) Option {
+	return WithConfigOptions(k8sconfig.WithBurst(burst))
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return WithConfigOptions(k8sconfig.WithTimeout(timeout))
+}
+
+func WithUserAgent(userAgent string) Option {
+	return WithConfigOptions(k8sconfig.WithUserAgent(userAgent))
+}
+
+func WithInsecureSkipVerify() Option {
+	return WithConfigOptions(k8sconfig.WithInsecureSkipVerify())
+}
+
+func WithCAData(caData []byte) Option {
+	return WithConfigOptions(k8sconfig.WithCAData(caData))
+}
+
+// WithClusterLabels attaches labels to the cluster when it is loaded, see Select
+func WithClusterLabels(clusterName string, labels map[string]string) Option {
+	return func(o *clientOptions) {
+		if o.clusterLabels[clusterName] == nil {
+			o.clusterLabels[clusterName] = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			o.clusterLabels[clusterName][k] = v
+		}
+	}
+}
+
+// WithRefuseUnhealthy makes GetClient return a client that fails every call when the
+// cached result of StartHealthChecks says the cluster is unhealthy
+func WithRefuseUnhealthy() Option {
+	return func(o *clientOptions) {
+		o.refuseUnhealthy = true
+	}
+}
+
+// WithRetryPolicy retries transient errors (429, 5xx, timeouts, dropped connections) and update
+// conflicts of every client from the registry, retry.WithPolicy overrides it for a single call
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(o *clientOptions) {
+		o.retryPolicy = &policy
+	}
+}
+
+// WithApplyOptions sets the field manager and force flag Apply uses on every client from the registry,
+// apply.WithOptions overrides them for a single call
+func WithApplyOptions(options apply.Options) Option {
+	return func(o *clientOptions) {
+		o.applyOptions = &options
+	}
+}
@@ -0,0 +1,24 @@
+package k8s
+
+import (
+	"testing"
+	k8sconfig "github.com/zhanghaohao/kubernetes-client/config"
+	"k8s.io/client-go/rest"
+)
+
+func TestClusterOptionsOverrideGlobalOptions(t *testing.T) {
+	o := newClientOptions([]Option{
+		WithQPS(20),
+		WithClusterOptions("prod", k8sconfig.WithQPS(100)),
+		WithBurst(40),
+	})
+	for clusterName, wantQPS := range map[string]float32{"prod": 100, "test": 20} {
+		kubeConfig, err := k8sconfig.ApplyOptions(&rest.Config{}, o.forCluster(clusterName)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kubeConfig.QPS != wantQPS || kubeConfig.Burst != 40 {
+			t.Errorf("%s: qps = %v, burst = %d, want %v and 40", clusterName, kubeConfig.QPS, kubeConfig.Burst, wantQPS)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package patch
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// StrategicMerge merges lists by their merge key, e.g. containers by name. Built-in kinds only.
+	StrategicMerge = types.StrategicMergePatchType
+	// Merge is RFC 7386, lists are replaced whole
+	Merge = types.MergePatchType
+	// JSON is RFC 6902, a list of operations such as {"op": "replace", "path": "/spec/replicas", "value": 3}
+	JSON = types.JSONPatchType
+)
+
+// Data converts a patch given as YAML or JSON to the JSON body sent for patchType
+func Data(patchType types.PatchType, input string) (data []byte, err error) {
+	switch patchType {
+	case StrategicMerge, Merge, JSON:
+	default:
+		return nil, fmt.Errorf("unsupported patch type %q", patchType)
+	}
+	data, err = yaml.YAMLToJSON([]byte(input))
+	if err != nil {
+		return
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return nil, fmt.Errorf("empty patch")
+	}
+	// a JSON patch is a list of operations, the other types an object
+	if isList := data[0] == '['; isList != (patchType == JSON) {
+		return nil, fmt.Errorf("patch is not a valid %s body", patchType)
+	}
+	return
+}
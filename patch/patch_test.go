@@ -0,0 +1,50 @@
+package patch
+
+import (
+	"testing"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestData(t *testing.T) {
+	tests := []struct {
+		patchType 			types.PatchType
+		input 				string
+		want 				string
+	}{
+		{Merge, "spec:\n  replicas: 3\n", `{"spec":{"replicas":3}}`},
+		{Merge, `{"spec":{"replicas":3}}`, `{"spec":{"replicas":3}}`},
+		{StrategicMerge, "spec:\n  template:\n    spec:\n      containers:\n      - name: nginx\n        image: nginx:1.19\n", `{"spec":{"template":{"spec":{"containers":[{"image":"nginx:1.19","name":"nginx"}]}}}}`},
+		{JSON, "- op: replace\n  path: /spec/replicas\n  value: 3\n", `[{"op":"replace","path":"/spec/replicas","value":3}]`},
+		{JSON, `[{"op":"remove","path":"/metadata/labels/tier"}]`, `[{"op":"remove","path":"/metadata/labels/tier"}]`},
+	}
+	for _, test := range tests {
+		data, err := Data(test.patchType, test.input)
+		if err != nil {
+			t.Errorf("Data(%s, %q): %v", test.patchType, test.input, err)
+			continue
+		}
+		if string(data) != test.want {
+			t.Errorf("Data(%s, %q) = %s, want %s", test.patchType, test.input, data, test.want)
+		}
+	}
+}
+
+func TestDataInvalid(t *testing.T) {
+	tests := []struct {
+		patchType 			types.PatchType
+		input 				string
+	}{
+		{types.ApplyPatchType, "spec:\n  replicas: 3\n"},
+		{Merge, ""},
+		{Merge, "null"},
+		{Merge, "spec: ["},
+		// a JSON patch must be a list of operations and the other types an object
+		{JSON, `{"spec":{"replicas":3}}`},
+		{Merge, `[{"op":"replace","path":"/spec/replicas","value":3}]`},
+	}
+	for _, test := range tests {
+		if _, err := Data(test.patchType, test.input); err == nil {
+			t.Errorf("Data(%s, %q): err = nil", test.patchType, test.input)
+		}
+	}
+}
@@ -1,14 +1,27 @@
 package pod
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
 	corev1 "k8s.io/api/core/v1"
 	"encoding/json"
 )
 
+const resourceType = "pod"
+
 type pod struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
 	err 					error
 }
 
@@ -36,12 +49,27 @@ type Pod interface {
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
 	ListPods(namespace string) (podList []PodInfo, err error)
 	GetLogs(namespace string, podName string) (logs string, err error)
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
+	ListPodsContext(ctx context.Context, namespace string) (podList []PodInfo, err error)
+	GetLogsContext(ctx context.Context, namespace string, podName string) (logs string, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *pod {
+func NewForClient(client kubernetes.Interface) *pod {
 	return &pod{
 		client: client,
 	}
@@ -51,26 +79,83 @@ func (c *pod) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *pod) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors, nil sends every request once
+func (c *pod) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+func (c *pod) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *pod) Create(input string) (err error) {
-	//todo
-	return
+	return c.CreateContext(context.Background(), input)
+}
+
+// CreateContext is not implemented for pods
+func (c *pod) CreateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "create")
 }
 
 func (c *pod) Update(input string) (err error) {
-	//todo
-	return
+	return c.UpdateContext(context.Background(), input)
+}
+
+// UpdateContext is not implemented for pods
+func (c *pod) UpdateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "update")
+}
+
+func (c *pod) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext is not implemented for pods, the multi-document Apply sends them to the dynamic client
+func (c *pod) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "apply")
 }
 
 func (c *pod) Delete(namespace string, name string) (err error) {
-	//todo
-	return
+	return c.DeleteContext(context.Background(), namespace, name)
+}
+
+// DeleteContext is not implemented for pods
+func (c *pod) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	return k8serrors.NotSupported(c.clusterName, resourceType, "delete")
 }
 
 func (c *pod) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *pod) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	pod, err := c.client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	var pod *corev1.Pod
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		pod, err = c.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
 	d, err := json.Marshal(pod)
 	if err != nil {
 		return "", err
@@ -79,33 +164,122 @@ func (c *pod) Get(namespace string, name string) (ret string, err error) {
 	return
 }
 
+func (c *pod) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the pod with a YAML or JSON body and returns the patched object
+func (c *pod) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var pod *corev1.Pod
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		pod, err = c.client.CoreV1().Pods(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(pod)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *pod) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of pods, options.Limit and options.Continue page through the rest
+func (c *pod) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var pods *corev1.PodList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		pods, err = c.client.CoreV1().Pods(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&pods.ListMeta)
+	for i := range pods.Items {
+		d, err := json.Marshal(&pods.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *pod) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of pods lazily, one request per options.Limit items
+func (c *pod) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to pods until ctx is done, reconnecting and resuming on its own
+func (c *pod) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.CoreV1().Pods(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
+
 func (c *pod) ListPods(namespace string) (podList []PodInfo, err error) {
+	return c.ListPodsContext(context.Background(), namespace)
+}
+
+func (c *pod) ListPodsContext(ctx context.Context, namespace string) (podList []PodInfo, err error) {
 	if c.err != nil {
 		return nil, c.err
 	}
-	pods, err := c.client.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	var pods *corev1.PodList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		pods, err = c.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		return
+	})
 	if err != nil {
+		err = c.wrapErr(err, namespace, "")
 		return
 	}
-	//body, err := json.Marshal(pods)
-	//if err != nil {
-	//	logger.Error.Println(err)
-	//	return
-	//}
-	//var out bytes.Buffer
-	//err = json.Indent(&out, body, "", "    ")
-	//if err != nil {
-	//	logger.Error.Println(err)
-	//	return
-	//}
-	//logger.Info.Println(out.String())
 	for _, e := range pods.Items {
 		var pod PodInfo
 		pod.PodName = e.Name
 		pod.Status = string(e.Status.Phase)
 		pod.HostIP = e.Status.HostIP
 		pod.PodIP = e.Status.PodIP
-		pod.StartTime = e.Status.StartTime.Format("2006-01-02 15:04:05")
+		if e.Status.StartTime != nil {
+			pod.StartTime = e.Status.StartTime.Format("2006-01-02 15:04:05")
+		}
 		for _, c := range e.Status.ContainerStatuses {
 			var container ContainerInfo
 			container.Name = c.Name
@@ -123,14 +297,23 @@ func (c *pod) ListPods(namespace string) (podList []PodInfo, err error) {
 }
 
 func (c *pod) GetLogs(namespace string, podName string) (logs string, err error) {
+	return c.GetLogsContext(context.Background(), namespace, podName)
+}
+
+func (c *pod) GetLogsContext(ctx context.Context, namespace string, podName string) (logs string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
 	opts := &corev1.PodLogOptions{
 		Timestamps: true,
 	}
-	resp, err := c.client.CoreV1().Pods(namespace).GetLogs(podName, opts).DoRaw()
+	var resp []byte
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		resp, err = c.client.CoreV1().Pods(namespace).GetLogs(podName, opts).DoRaw(ctx)
+		return
+	})
 	if err != nil {
+		err = c.wrapErr(err, namespace, podName)
 		return
 	}
 	logs = string(resp)
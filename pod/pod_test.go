@@ -0,0 +1,159 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPod(name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "default",
+			Labels: labels,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: "10.0.0.1",
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "nginx",
+				Ready: true,
+				RestartCount: 1,
+				Image: "nginx:1.17",
+			}},
+		},
+	}
+}
+
+func TestPodGetAndList(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(
+		newPod("nginx-1", map[string]string{"app": "nginx"}),
+		newPod("redis-1", map[string]string{"app": "redis"}),
+	))
+	ret, err := c.Get("default", "nginx-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := new(corev1.Pod)
+	json.Unmarshal([]byte(ret), pod)
+	if pod.Status.PodIP != "10.0.0.1" {
+		t.Errorf("podIP = %s, want 10.0.0.1", pod.Status.PodIP)
+	}
+	if _, err := c.Get("default", "missing"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get missing: err = %v, want ErrNotFound", err)
+	}
+	page, err := c.List("default", list.Options{LabelSelector: "app=nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("List(app=nginx) returned %d items, want 1", len(page.Items))
+	}
+}
+
+func TestListPods(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newPod("nginx-1", nil)))
+	podList, err := c.ListPods("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(podList) != 1 {
+		t.Fatalf("ListPods returned %d pods, want 1", len(podList))
+	}
+	pod := podList[0]
+	if pod.PodName != "nginx-1" || pod.Status != string(corev1.PodRunning) || len(pod.Containers) != 1 {
+		t.Fatalf("ListPods = %+v", pod)
+	}
+	if container := pod.Containers[0]; !container.Ready || container.RestartCount != 1 || container.Image != "nginx:1.17" {
+		t.Errorf("container = %+v", container)
+	}
+}
+
+func TestPodPatch(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newPod("nginx-1", nil)))
+	ret, err := c.Patch("default", "nginx-1", types.StrategicMergePatchType, "metadata:\n  labels:\n    debug: \"true\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := new(corev1.Pod)
+	json.Unmarshal([]byte(ret), pod)
+	if pod.Labels["debug"] != "true" {
+		t.Errorf("labels after Patch = %v, want debug=true", pod.Labels)
+	}
+}
+
+func TestPodWriteIsNotSupported(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newPod("nginx-1", nil)))
+	input := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: nginx-1\n  namespace: default\n"
+	if err := c.Create(input); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Create: err = %v, want ErrNotSupported", err)
+	}
+	if err := c.Update(input); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Update: err = %v, want ErrNotSupported", err)
+	}
+	if err := c.Apply(input); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Apply: err = %v, want ErrNotSupported", err)
+	}
+	if err := c.Delete("default", "nginx-1"); !errors.Is(err, k8serrors.ErrNotSupported) {
+		t.Errorf("Delete: err = %v, want ErrNotSupported", err)
+	}
+	if _, err := c.Get("default", "nginx-1"); err != nil {
+		t.Errorf("Get after Delete: err = %v, want the pod untouched", err)
+	}
+}
+
+func TestPodWatch(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(newPod("nginx-1", nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := c.Watch(ctx, "default", watch.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != watch.Added || event.Name != "nginx-1" {
+			t.Errorf("first event = %+v, want Added nginx-1", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event before the timeout")
+	}
+}
+
+func TestPodGetRetriesServerErrors(t *testing.T) {
+	client := fake.NewSimpleClientset(newPod("nginx-1", nil))
+	attempts := 0
+	client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, apierrors.NewServiceUnavailable("apiserver restarting")
+		}
+		return false, nil, nil
+	})
+	c := NewForClient(client)
+	c.SetRetryPolicy(&retry.Policy{
+		MaxAttempts: 3,
+		InitialBackoff: time.Millisecond,
+		Multiplier: 1,
+	})
+	if _, err := c.Get("default", "nginx-1"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
@@ -0,0 +1,62 @@
+package prometheus
+
+import (
+	"strconv"
+	prom "github.com/prometheus/client_golang/prometheus"
+	k8s "github.com/zhanghaohao/kubernetes-client"
+)
+
+// Metrics exports k8s.RequestMetric observations as Prometheus counters and a latency histogram
+type Metrics struct {
+	requests 				*prom.CounterVec
+	errors 					*prom.CounterVec
+	latency 				*prom.HistogramVec
+}
+
+// New builds the collectors, namespace prefixes every metric name (e.g. "myapp")
+func New(namespace string) *Metrics {
+	labels := []string{"cluster", "resource", "verb"}
+	return &Metrics{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kubernetes_client",
+			Name: "requests_total",
+			Help: "Kubernetes API requests by cluster, resource type, verb and status code.",
+		}, append(labels, "code")),
+		errors: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kubernetes_client",
+			Name: "request_errors_total",
+			Help: "Failed Kubernetes API requests by cluster, resource type and verb.",
+		}, labels),
+		latency: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "kubernetes_client",
+			Name: "request_duration_seconds",
+			Help: "Kubernetes API request latency by cluster, resource type and verb.",
+			Buckets: k8s.DefaultLatencyBuckets,
+		}, labels),
+	}
+}
+
+func (m *Metrics) Register(registerer prom.Registerer) error {
+	for _, c := range []prom.Collector{m.requests, m.errors, m.latency} {
+		if err := registerer.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Metrics) Observe(metric k8s.RequestMetric) {
+	resource := metric.ResourceType.String()
+	code := "error"
+	if metric.StatusCode != 0 {
+		code = strconv.Itoa(metric.StatusCode)
+	}
+	m.requests.WithLabelValues(metric.Cluster, resource, metric.Verb, code).Inc()
+	if metric.Err != nil {
+		m.errors.WithLabelValues(metric.Cluster, resource, metric.Verb).Inc()
+	}
+	m.latency.WithLabelValues(metric.Cluster, resource, metric.Verb).Observe(metric.Duration.Seconds())
+}
@@ -0,0 +1,43 @@
+package prometheus
+
+import (
+	"errors"
+	"testing"
+	"time"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	k8s "github.com/zhanghaohao/kubernetes-client"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("myapp")
+	registry := prom.NewRegistry()
+	if err := m.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	m.Observe(k8s.RequestMetric{Cluster: "prod", ResourceType: k8s.KubernetesPod, Verb: "list", StatusCode: 200, Duration: 30 * time.Millisecond})
+	m.Observe(k8s.RequestMetric{Cluster: "prod", ResourceType: k8s.KubernetesPod, Verb: "list", StatusCode: 500, Err: errors.New("500 Internal Server Error")})
+	m.Observe(k8s.RequestMetric{Cluster: "prod", ResourceType: k8s.KubernetesPod, Verb: "list", Err: errors.New("connection refused")})
+	for code, want := range map[string]float64{"200": 1, "500": 1, "error": 1} {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues("prod", "pod", "list", code)); got != want {
+			t.Errorf("requests code=%s = %v, want %v", code, got, want)
+		}
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues("prod", "pod", "list")); got != 2 {
+		t.Errorf("errors = %v, want 2", got)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "myapp_kubernetes_client_request_duration_seconds" {
+			continue
+		}
+		if histogram := family.GetMetric()[0].GetHistogram(); histogram.GetSampleCount() != 3 {
+			t.Errorf("latency samples = %d, want 3", histogram.GetSampleCount())
+		}
+		return
+	}
+	t.Error("the latency histogram is not registered")
+}
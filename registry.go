@@ -0,0 +1,278 @@
+package k8s
+
+import (
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	k8sconfig "github.com/zhanghaohao/kubernetes-client/config"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+type ClusterMetadata struct {
+	// Source is where the cluster was loaded from, e.g. a kubeconfig path or service account dir
+	Source 					string
+	Server 					string
+	Context 				string
+	// Labels such as env=prod or region=eu, used by Select to build a ClusterGroup
+	Labels 					map[string]string
+	Annotations 			map[string]string
+	LoadedAt 				time.Time
+}
+
+type cluster struct {
+	client 					kubernetes.Interface
+	// config is the final rest config the client was built from, nil for clients passed to Load
+	config 					*rest.Config
+	// dynamic is nil for clients passed to Load, like config
+	dynamic 				dynamic.Interface
+	metadata 				ClusterMetadata
+	mapperOnce 				sync.Once
+	mapper 					meta.RESTMapper
+	impersonationOnce 		sync.Once
+	transport 				http.RoundTripper
+	rateLimiter 			flowcontrol.RateLimiter
+	transportErr 			error
+}
+
+// k8sClients is safe for concurrent use, clusters can be loaded and removed at runtime
+type k8sClients struct {
+	mu 						sync.RWMutex
+	clients 				map[string]*cluster
+	health 					map[string]ClusterHealth
+	options 				*clientOptions
+	stops 					[]func()
+}
+
+func newK8sClients(opts ...Option) *k8sClients {
+	return &k8sClients{
+		clients: make(map[string]*cluster),
+		health: make(map[string]ClusterHealth),
+		options: newClientOptions(opts),
+	}
+}
+
+func (m ClusterMetadata) copy() ClusterMetadata {
+	m.Labels = copyStringMap(m.Labels)
+	m.Annotations = copyStringMap(m.Annotations)
+	return m
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (k *k8sClients) GetClient(clusterName string) K8SClient {
+	r := &k8sClient{
+		clients: k,
+		clusterName: clusterName,
+	}
+	if !k.Has(clusterName) {
+		r.err = k8serrors.ClusterNotFound(clusterName)
+		return r
+	}
+	if k.options.refuseUnhealthy {
+		if health, ok := k.CachedHealth(clusterName); ok && !health.Healthy {
+			r.err = fmt.Errorf("cluster %s is unhealthy: %w", clusterName, health.Err)
+		}
+	}
+	return r
+}
+
+func (k *k8sClients) Load(clusterName string, client kubernetes.Interface) {
+	k.LoadWithMetadata(clusterName, client, ClusterMetadata{})
+}
+
+func (k *k8sClients) LoadWithMetadata(clusterName string, client kubernetes.Interface, metadata ClusterMetadata) {
+	k.load(clusterName, client, nil, nil, metadata)
+}
+
+// loadConfig builds the cluster's client from kubeConfig with the registry options and registers it
+func (k *k8sClients) loadConfig(clusterName string, kubeConfig *rest.Config, metadata ClusterMetadata) (err error) {
+	kubeConfig, err = k8sconfig.ApplyOptions(kubeConfig, k.options.forCluster(clusterName)...)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %w", clusterName, err)
+	}
+	client, err := k8sconfig.BuildKubernetesClientFromKubeConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %w", clusterName, err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %w", clusterName, err)
+	}
+	k.load(clusterName, client, dynamicClient, kubeConfig, metadata)
+	return
+}
+
+func (k *k8sClients) load(clusterName string, client kubernetes.Interface, dynamicClient dynamic.Interface, kubeConfig *rest.Config, metadata ClusterMetadata) {
+	metadata = metadata.copy()
+	// labels given to the constructor options apply unless the metadata sets the same key
+	if labels := k.options.clusterLabels[clusterName]; len(labels) != 0 {
+		merged := copyStringMap(labels)
+		for key, value := range metadata.Labels {
+			merged[key] = value
+		}
+		metadata.Labels = merged
+	}
+	if metadata.LoadedAt.IsZero() {
+		metadata.LoadedAt = time.Now()
+	}
+	k.mu.Lock()
+	k.clients[clusterName] = &cluster{
+		client: client,
+		config: kubeConfig,
+		dynamic: dynamicClient,
+		metadata: metadata,
+	}
+	// health probed with the old client says nothing about the new one
+	delete(k.health, clusterName)
+	k.mu.Unlock()
+}
+
+func (k *k8sClients) Remove(clusterName string) (ok bool) {
+	k.mu.Lock()
+	_, ok = k.clients[clusterName]
+	delete(k.clients, clusterName)
+	delete(k.health, clusterName)
+	k.mu.Unlock()
+	return
+}
+
+func (k *k8sClients) List() (clusterNames []string) {
+	k.mu.RLock()
+	for name := range k.clients {
+		clusterNames = append(clusterNames, name)
+	}
+	k.mu.RUnlock()
+	sort.Strings(clusterNames)
+	return
+}
+
+func (k *k8sClients) Has(clusterName string) bool {
+	k.mu.RLock()
+	_, ok := k.clients[clusterName]
+	k.mu.RUnlock()
+	return ok
+}
+
+func (k *k8sClients) Metadata(clusterName string) (metadata ClusterMetadata, ok bool) {
+	// copy under the lock, SetLabels changes the metadata in place
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	c, ok := k.clients[clusterName]
+	if !ok {
+		return
+	}
+	return c.metadata.copy(), true
+}
+
+// SetLabels replaces the labels of a registered cluster
+func (k *k8sClients) SetLabels(clusterName string, labels map[string]string) (ok bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	c, ok := k.clients[clusterName]
+	if !ok {
+		return
+	}
+	c.metadata.Labels = copyStringMap(labels)
+	return
+}
+
+// Close stops every background goroutine started on the registry, e.g. kubeconfig watchers
+func (k *k8sClients) Close() {
+	k.mu.Lock()
+	stops := k.stops
+	k.stops = nil
+	k.mu.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+func (k *k8sClients) addStop(stop func()) {
+	k.mu.Lock()
+	k.stops = append(k.stops, stop)
+	k.mu.Unlock()
+}
+
+// impersonatedConfig returns a rest config sending the impersonation headers for impersonate.
+// All of the cluster's impersonating clients share one transport and one rate limiter.
+func (c *cluster) impersonatedConfig(clusterName string, impersonate rest.ImpersonationConfig) (kubeConfig *rest.Config, err error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("cluster %s was loaded without a rest config, impersonation needs a cluster built by a New* constructor", clusterName)
+	}
+	c.impersonationOnce.Do(func() {
+		c.transport, c.transportErr = rest.TransportFor(c.config)
+		c.rateLimiter = c.config.RateLimiter
+		if c.rateLimiter == nil && c.config.QPS >= 0 {
+			qps, burst := c.config.QPS, c.config.Burst
+			if qps == 0 {
+				qps = rest.DefaultQPS
+			}
+			if burst == 0 {
+				burst = rest.DefaultBurst
+			}
+			c.rateLimiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+		}
+	})
+	if c.transportErr != nil {
+		return nil, fmt.Errorf("cluster %s: %w", clusterName, c.transportErr)
+	}
+	/*
+	the shared transport already authenticates as the cluster's own credentials,
+	only the impersonation headers are added on top
+	 */
+	kubeConfig = &rest.Config{
+		Host: c.config.Host,
+		APIPath: c.config.APIPath,
+		ContentConfig: c.config.ContentConfig,
+		UserAgent: c.config.UserAgent,
+		Timeout: c.config.Timeout,
+		QPS: c.config.QPS,
+		Burst: c.config.Burst,
+		RateLimiter: c.rateLimiter,
+		Transport: c.transport,
+		Impersonate: impersonate,
+	}
+	return
+}
+
+func (c *cluster) impersonatedClient(clusterName string, impersonate rest.ImpersonationConfig) (client kubernetes.Interface, err error) {
+	kubeConfig, err := c.impersonatedConfig(clusterName, impersonate)
+	if err != nil {
+		return
+	}
+	return kubernetes.NewForConfig(kubeConfig)
+}
+
+func (c *cluster) impersonatedDynamic(clusterName string, impersonate rest.ImpersonationConfig) (client dynamic.Interface, err error) {
+	kubeConfig, err := c.impersonatedConfig(clusterName, impersonate)
+	if err != nil {
+		return
+	}
+	return dynamic.NewForConfig(kubeConfig)
+}
+
+// restMapper maps kinds to resources from the cluster's discovery, cached until a kind is not found
+func (c *cluster) restMapper() meta.RESTMapper {
+	c.mapperOnce.Do(func() {
+		c.mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.client.Discovery()))
+	})
+	return c.mapper
+}
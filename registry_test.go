@@ -0,0 +1,128 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// run with go test -race, the test itself only checks that the registry stays consistent
+func TestRegistryConcurrentAccess(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clusterNames := []string{"cluster1", "cluster2", "cluster3"}
+	for _, name := range clusterNames {
+		clients.LoadWithMetadata(name, fake.NewSimpleClientset(), ClusterMetadata{
+			Labels: map[string]string{"env": "prod"},
+		})
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				name := clusterNames[(i+j)%len(clusterNames)]
+				switch j % 6 {
+				case 0:
+					clients.Load(name, fake.NewSimpleClientset())
+				case 1:
+					clients.Remove(name)
+				case 2:
+					for _, listed := range clients.List() {
+						clients.Has(listed)
+					}
+				case 3:
+					// Load without metadata leaves the labels nil
+					if metadata, ok := clients.Metadata(name); ok && metadata.Labels != nil {
+						for key, value := range metadata.Labels {
+							_ = key + value
+						}
+						metadata.Labels["read"] = "by test"
+					}
+				case 4:
+					clients.SetLabels(name, map[string]string{"env": "prod", "writer": fmt.Sprint(i)})
+				case 5:
+					group, err := clients.Select("env=prod")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					group.Clusters()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, name := range clients.List() {
+		if !clients.Has(name) {
+			t.Errorf("cluster %s is listed but Has is false", name)
+		}
+		if _, ok := clients.Metadata(name); !ok {
+			t.Errorf("cluster %s is listed but has no metadata", name)
+		}
+	}
+}
+
+func TestMetadataIsACopy(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clients.LoadWithMetadata("cluster1", fake.NewSimpleClientset(), ClusterMetadata{
+		Labels: map[string]string{"env": "prod"},
+	})
+	metadata, ok := clients.Metadata("cluster1")
+	if !ok {
+		t.Fatal("cluster1 not found")
+	}
+	metadata.Labels["env"] = "test"
+	metadata, _ = clients.Metadata("cluster1")
+	if metadata.Labels["env"] != "prod" {
+		t.Errorf("env label = %q, changing the returned metadata must not change the registry", metadata.Labels["env"])
+	}
+}
+
+func TestSetLabels(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clients.Load("cluster1", fake.NewSimpleClientset())
+	if clients.SetLabels("missing", map[string]string{"env": "prod"}) {
+		t.Error("SetLabels on a missing cluster returned true")
+	}
+	labels := map[string]string{"env": "prod"}
+	if !clients.SetLabels("cluster1", labels) {
+		t.Fatal("SetLabels on cluster1 returned false")
+	}
+	labels["env"] = "test"
+	group, err := clients.Select("env=prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusterNames := group.Clusters(); len(clusterNames) != 1 || clusterNames[0] != "cluster1" {
+		t.Errorf("Select(env=prod) = %v, want [cluster1]", clusterNames)
+	}
+}
+
+func TestLoadRemove(t *testing.T) {
+	clients := New()
+	defer clients.Close()
+	clients.Load("cluster2", fake.NewSimpleClientset())
+	clients.Load("cluster1", fake.NewSimpleClientset())
+	clients.Load("cluster1", fake.NewSimpleClientset())
+	if clusterNames := clients.List(); len(clusterNames) != 2 || clusterNames[0] != "cluster1" || clusterNames[1] != "cluster2" {
+		t.Errorf("List() = %v, want [cluster1 cluster2]", clusterNames)
+	}
+	if !clients.Remove("cluster1") {
+		t.Error("Remove(cluster1) returned false")
+	}
+	if clients.Remove("cluster1") {
+		t.Error("second Remove(cluster1) returned true")
+	}
+	if clients.Has("cluster1") {
+		t.Error("cluster1 is still registered after Remove")
+	}
+	if _, err := clients.GetClient("cluster1").Pod().Get("default", "nginx"); !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("Get on a removed cluster: err = %v, want ErrClusterNotFound", err)
+	}
+}
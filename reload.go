@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"sync"
+	"time"
+	k8sconfig "github.com/zhanghaohao/kubernetes-client/config"
+)
+
+const (
+	defaultReloadInterval = 10 * time.Second
+	// readErrorGrace is how many ticks a file may stay unreadable, e.g. while it is being replaced, before it is reported
+	readErrorGrace = 1
+)
+
+type ReloadOp string
+
+const (
+	ReloadAdded ReloadOp = "added"
+	ReloadUpdated ReloadOp = "updated"
+	ReloadRemoved ReloadOp = "removed"
+)
+
+type ReloadEvent struct {
+	ClusterName 			string
+	Path 					string
+	Op 						ReloadOp
+	// Err is set when the changed kubeconfig could not be loaded or the file could not be read
+	// for longer than one tick, the previous client is kept
+	Err 					error
+}
+
+type ReloadFunc func(event ReloadEvent)
+
+type kubeconfigWatcher struct {
+	clients 				*k8sClients
+	paths 					[]KubeConfigPath
+	interval 				time.Duration
+	onReload 				ReloadFunc
+	hashes 					map[string][]byte
+	// readErrors counts the ticks each cluster's file has been unreadable in a row
+	readErrors 				map[string]int
+	stopCh 					chan struct{}
+	stopOnce 				sync.Once
+}
+
+// WatchKubeconfigPaths polls every path and rebuilds that cluster's client when the file content changes.
+// Clients handed out by GetClient pick up the new credentials on their next call.
+// The returned stop func (or Close) ends the watch.
+func (k *k8sClients) WatchKubeconfigPaths(paths []KubeConfigPath, interval time.Duration, onReload ReloadFunc) (stop func()) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	w := &kubeconfigWatcher{
+		clients: k,
+		paths: paths,
+		interval: interval,
+		onReload: onReload,
+		hashes: make(map[string][]byte),
+		readErrors: make(map[string]int),
+		stopCh: make(chan struct{}),
+	}
+	for _, path := range paths {
+		w.hashes[path.ClusterName], _ = hashFile(path.Path)
+	}
+	go w.run()
+	stop = func() {
+		w.stopOnce.Do(func() {
+			close(w.stopCh)
+		})
+	}
+	k.addStop(stop)
+	return
+}
+
+func (w *kubeconfigWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			for _, path := range w.paths {
+				w.check(path)
+			}
+		}
+	}
+}
+
+func (w *kubeconfigWatcher) check(path KubeConfigPath) {
+	event := ReloadEvent{
+		ClusterName: path.ClusterName,
+		Path: path.Path,
+		Op: ReloadUpdated,
+	}
+	hash, err := hashFile(path.Path)
+	if err != nil {
+		// the file may be in the middle of being replaced, report it once it stays unreadable
+		w.readErrors[path.ClusterName]++
+		if w.readErrors[path.ClusterName] == readErrorGrace+1 {
+			event.Err = err
+			w.emit(event)
+		}
+		return
+	}
+	w.readErrors[path.ClusterName] = 0
+	if bytes.Equal(hash, w.hashes[path.ClusterName]) {
+		return
+	}
+	metadata, _ := w.clients.Metadata(path.ClusterName)
+	metadata.Source = path.Path
+	metadata.LoadedAt = time.Now()
+	kubeConfig, err := k8sconfig.ReadKubeConfigFile(path.Path)
+	if err == nil {
+		err = w.clients.loadConfig(path.ClusterName, kubeConfig, metadata)
+	}
+	event.Err = err
+	// report each change once, a broken file is retried when it changes again
+	w.hashes[path.ClusterName] = hash
+	w.emit(event)
+}
+
+func (w *kubeconfigWatcher) emit(event ReloadEvent) {
+	if w.onReload != nil {
+		w.onReload(event)
+	}
+}
+
+func hashFile(path string) (hash []byte, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
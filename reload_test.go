@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextReloadEvent(t *testing.T, events <-chan ReloadEvent) ReloadEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload event before the timeout")
+	}
+	return ReloadEvent{}
+}
+
+// writeFileAtomic replaces the file in one step, so the watcher never reads it half written
+func writeFileAtomic(t *testing.T, path string, data string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path+".tmp", []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchKubeconfigPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.yaml")
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(testKubeConfig, "prod", "https://prod-1:6443")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	paths := []KubeConfigPath{{ClusterName: "prod", Path: path}}
+	clients, err := NewFromKubeconfigPaths(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Close()
+	events := make(chan ReloadEvent, 10)
+	clients.WatchKubeconfigPaths(paths, 10*time.Millisecond, func(event ReloadEvent) {
+		events <- event
+	})
+
+	writeFileAtomic(t, path, fmt.Sprintf(testKubeConfig, "prod", "https://prod-2:6443"))
+	event := nextReloadEvent(t, events)
+	if event.Op != ReloadUpdated || event.ClusterName != "prod" || event.Err != nil {
+		t.Errorf("event after rewrite = %+v, want prod updated without error", event)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadUpdated || event.ClusterName != "prod" || !os.IsNotExist(event.Err) {
+		t.Errorf("event after remove = %+v, want prod with a not exist error", event)
+	}
+	// a file that stays missing is reported once, and the cluster keeps its client
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case event := <-events:
+		t.Errorf("second event for the missing file: %+v", event)
+	default:
+	}
+	if !clients.Has("prod") {
+		t.Error("prod was unregistered, want the previous client kept")
+	}
+}
@@ -0,0 +1,200 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// Policy is an exponential backoff with jitter, MaxAttempts counts the first try
+type Policy struct {
+	MaxAttempts 			int
+	InitialBackoff 			time.Duration
+	MaxBackoff 				time.Duration
+	Multiplier 				float64
+	// Jitter randomizes each backoff by up to this fraction, e.g. 0.2 gives +/-20%
+	Jitter 					float64
+}
+
+var DefaultPolicy = Policy{
+	MaxAttempts: 5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+	Multiplier: 2,
+	Jitter: 0.2,
+}
+
+// Error is returned when an operation still failed after more than one attempt
+type Error struct {
+	Attempts 				int
+	Err 					error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Report collects the number of attempts made by the calls sharing its context
+type Report struct {
+	mu 						sync.Mutex
+	attempts 				int
+}
+
+func (r *Report) Attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts
+}
+
+func (r *Report) add(attempts int) {
+	r.mu.Lock()
+	r.attempts += attempts
+	r.mu.Unlock()
+}
+
+type policyKey struct{}
+
+type reportKey struct{}
+
+// WithPolicy overrides the client's retry policy for calls made with the returned context
+func WithPolicy(ctx context.Context, policy Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+// WithReport returns a context whose calls record how many attempts they made into report
+func WithReport(ctx context.Context) (context.Context, *Report) {
+	report := new(Report)
+	return context.WithValue(ctx, reportKey{}, report), report
+}
+
+func (p Policy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+			backoff = float64(p.MaxBackoff)
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// IsTransient reports errors worth retrying: throttling, server errors, timeouts and dropped connections
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if statusErr, ok := statusError(err); ok {
+		return apierrors.IsTooManyRequests(statusErr) ||
+			apierrors.IsServerTimeout(statusErr) ||
+			apierrors.IsTimeout(statusErr) ||
+			apierrors.IsInternalError(statusErr) ||
+			apierrors.IsServiceUnavailable(statusErr) ||
+			apierrors.IsUnexpectedServerError(statusErr) ||
+			statusErr.(apierrors.APIStatus).Status().Code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) || utilnet.IsConnectionRefused(err)
+}
+
+func IsConflict(err error) bool {
+	statusErr, ok := statusError(err)
+	return ok && apierrors.IsConflict(statusErr)
+}
+
+// statusError finds the API status error behind wrappers, the apierrors helpers only look at err itself
+func statusError(err error) (statusErr error, ok bool) {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return
+	}
+	statusErr, ok = status.(error)
+	return
+}
+
+// Do runs fn until it succeeds, fails permanently, ctx ends or the policy runs out of attempts.
+// The policy comes from ctx (WithPolicy), else clientPolicy, else fn runs once.
+// Conflicts are retried only when retryConflicts is set; fn is expected to re-read the object then.
+func Do(ctx context.Context, clientPolicy *Policy, retryConflicts bool, fn func() error) (err error) {
+	return do(ctx, clientPolicy, func(err error) bool {
+		return IsTransient(err) || (retryConflicts && IsConflict(err))
+	}, fn)
+}
+
+// DoNonIdempotent is Do for requests that must not reach the server twice, such as create and delete.
+// A timeout or dropped connection may come after the server acted, so only 429 and 503 are retried:
+// the server sends them before doing anything.
+func DoNonIdempotent(ctx context.Context, clientPolicy *Policy, fn func() error) (err error) {
+	return do(ctx, clientPolicy, isRejected, fn)
+}
+
+func isRejected(err error) bool {
+	statusErr, ok := statusError(err)
+	return ok && (apierrors.IsTooManyRequests(statusErr) || apierrors.IsServiceUnavailable(statusErr))
+}
+
+func do(ctx context.Context, clientPolicy *Policy, retryable func(err error) bool, fn func() error) (err error) {
+	policy := Policy{MaxAttempts: 1}
+	if clientPolicy != nil {
+		policy = *clientPolicy
+	}
+	if p, ok := ctx.Value(policyKey{}).(Policy); ok {
+		policy = p
+	}
+	attempts := 0
+	defer func() {
+		if report, ok := ctx.Value(reportKey{}).(*Report); ok {
+			report.add(attempts)
+		}
+		if err != nil && attempts > 1 {
+			err = &Error{
+				Attempts: attempts,
+				Err: err,
+			}
+		}
+	}()
+	for {
+		attempts++
+		err = fn()
+		if err == nil {
+			return
+		}
+		if !retryable(err) {
+			return
+		}
+		if attempts >= policy.MaxAttempts {
+			return
+		}
+		wait := policy.Backoff(attempts)
+		// honor Retry-After sent with 429 and 5xx responses
+		if statusErr, ok := statusError(err); ok {
+			if seconds, ok := apierrors.SuggestsClientDelay(statusErr); ok && time.Duration(seconds)*time.Second > wait {
+				wait = time.Duration(seconds) * time.Second
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// callers check for the cancellation, the last error is kept for the message
+			return fmt.Errorf("%w, last error: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testPolicy = Policy{
+	MaxAttempts: 3,
+	InitialBackoff: time.Millisecond,
+	Multiplier: 1,
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+		Multiplier: 2,
+	}
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		10: time.Second,
+	} {
+		if got := p.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+	p.Jitter = 0.2
+	varied := false
+	for i := 0; i < 100; i++ {
+		got := p.Backoff(2)
+		if got < 160*time.Millisecond || got > 240*time.Millisecond {
+			t.Fatalf("Backoff(2) with 20%% jitter = %s, want 160ms to 240ms", got)
+		}
+		if got != 200*time.Millisecond {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Error("jitter never changed the backoff")
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	gr := schema.GroupResource{Resource: "deployments"}
+	tests := []struct {
+		err 				error
+		want 				bool
+	}{
+		{nil, false},
+		{apierrors.NewTooManyRequests("slow down", 1), true},
+		{apierrors.NewServiceUnavailable("restarting"), true},
+		{apierrors.NewInternalError(errors.New("etcd")), true},
+		{apierrors.NewTimeoutError("timeout", 1), true},
+		{fmt.Errorf("update nginx: %w", apierrors.NewServiceUnavailable("restarting")), true},
+		{io.EOF, true},
+		{apierrors.NewNotFound(gr, "nginx"), false},
+		{apierrors.NewConflict(gr, "nginx", errors.New("modified")), false},
+		{apierrors.NewBadRequest("bad"), false},
+		{errors.New("invalid manifest"), false},
+	}
+	for _, test := range tests {
+		if got := IsTransient(test.err); got != test.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+	if !IsConflict(fmt.Errorf("update nginx: %w", apierrors.NewConflict(gr, "nginx", errors.New("modified")))) {
+		t.Error("IsConflict of a wrapped conflict = false")
+	}
+}
+
+// failing returns an fn that fails with errs in turn and then succeeds, counting its calls
+func failing(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	}
+}
+
+func TestDo(t *testing.T) {
+	unavailable := apierrors.NewServiceUnavailable("restarting")
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "deployments"}, "nginx", errors.New("modified"))
+
+	calls := 0
+	if err := Do(context.Background(), &testPolicy, false, failing(&calls, unavailable, unavailable)); err != nil || calls != 3 {
+		t.Errorf("two transient errors: err = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err := Do(context.Background(), &testPolicy, false, failing(&calls, unavailable, unavailable, unavailable))
+	var retryErr *Error
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 || !apierrors.IsServiceUnavailable(retryErr.Err) {
+		t.Errorf("out of attempts: err = %v, want an *Error wrapping the last error after 3 attempts", err)
+	}
+
+	calls = 0
+	if err := Do(context.Background(), &testPolicy, false, failing(&calls, conflict)); !apierrors.IsConflict(err) || calls != 1 {
+		t.Errorf("conflict: err = %v after %d calls, want it returned after 1", err, calls)
+	}
+	calls = 0
+	if err := Do(context.Background(), &testPolicy, true, failing(&calls, conflict)); err != nil || calls != 2 {
+		t.Errorf("conflict with retryConflicts: err = %v after %d calls, want success after 2", err, calls)
+	}
+
+	// without a policy fn runs once, a policy from the context wins over the client's
+	calls = 0
+	Do(context.Background(), nil, false, failing(&calls, unavailable))
+	if calls != 1 {
+		t.Errorf("no policy: %d calls, want 1", calls)
+	}
+	calls = 0
+	ctx, report := WithReport(WithPolicy(context.Background(), Policy{MaxAttempts: 2}))
+	Do(ctx, &testPolicy, false, failing(&calls, unavailable, unavailable, unavailable))
+	if calls != 2 || report.Attempts() != 2 {
+		t.Errorf("context policy: %d calls, %d reported, want 2", calls, report.Attempts())
+	}
+}
+
+func TestDoNonIdempotent(t *testing.T) {
+	calls := 0
+	if err := DoNonIdempotent(context.Background(), &testPolicy, failing(&calls, apierrors.NewTooManyRequests("slow down", 0))); err != nil || calls != 2 {
+		t.Errorf("429: err = %v after %d calls, want success after 2", err, calls)
+	}
+	// the server may have acted before the connection dropped or the error was sent
+	for _, failure := range []error{io.EOF, apierrors.NewInternalError(errors.New("etcd"))} {
+		calls = 0
+		if err := DoNonIdempotent(context.Background(), &testPolicy, failing(&calls, failure)); err == nil || calls != 1 {
+			t.Errorf("%v: err = %v after %d calls, want it returned after 1", failure, err, calls)
+		}
+	}
+}
+
+func TestDoStopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{MaxAttempts: 5, InitialBackoff: time.Hour}
+	calls := 0
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := Do(ctx, &policy, false, failing(&calls, apierrors.NewServiceUnavailable("restarting")))
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
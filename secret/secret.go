@@ -1,27 +1,67 @@
 package secret
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
 	"sigs.k8s.io/yaml"
 	"encoding/json"
 )
 
+const resourceType = "secret"
+
 type secret struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
 	err 					error
 }
 
+type SecretInfo struct {
+	Namespace 				string
+	Name 					string
+	Labels 					map[string]string
+	Annotations 			map[string]string
+	ResourceVersion 		string
+	Type 					string
+	Data 					map[string][]byte
+}
+
 type Secret interface {
 	SetErr(err error)
 	Create(input string) (err error)
 	Delete(namespace string, name string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
+	CreateContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, name string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
+	ListSecrets(namespace string, labelSelector string) (secretList []SecretInfo, err error)
+	ListSecretsContext(ctx context.Context, namespace string, labelSelector string) (secretList []SecretInfo, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *secret {
+func NewForClient(client kubernetes.Interface) *secret {
 	return &secret{
 		client: client,
 	}
@@ -31,70 +71,291 @@ func (c *secret) SetErr(err error)  {
 	c.err = err
 }
 
+func (c *secret) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors and update conflicts, nil sends every request once
+func (c *secret) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+// SetApplyOptions sets the field manager and force flag of Apply, nil uses the defaults
+func (c *secret) SetApplyOptions(options *apply.Options) {
+	c.applyOptions = options
+}
+
+func (c *secret) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *secret) Create(input string) (err error) {
+	return c.CreateContext(context.Background(), input)
+}
+
+func (c *secret) CreateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	secret := new(corev1.Secret)
 	err = yaml.Unmarshal([]byte(input), secret)
 	if err != nil {
-		return
-	}
-	// output for debug
-	_, err = yaml.Marshal(secret)
-	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := secret.Namespace
-	_, err = c.client.CoreV1().Secrets(namespace).Create(secret)
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.client.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, secret.Name)
 	}
 	return
 }
 
 func (c *secret) Delete(namespace string, name string) (err error) {
+	return c.DeleteContext(context.Background(), namespace, name)
+}
+
+func (c *secret) DeleteContext(ctx context.Context, namespace string, name string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	err = c.client.CoreV1().Secrets(namespace).Delete(name, &metav1.DeleteOptions{})
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.client.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, name)
 	}
 	return
 }
 
 func (c *secret) Update(input string) (err error) {
+	return c.UpdateContext(context.Background(), input)
+}
+
+func (c *secret) UpdateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	secret := new(corev1.Secret)
 	err = yaml.Unmarshal([]byte(input), secret)
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
-	// output for debug
-	_, err = yaml.Marshal(secret)
+	namespace := secret.Namespace
+	conflict := false
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		if conflict {
+			// reapply our object on top of the live resourceVersion
+			current, err := c.client.CoreV1().Secrets(namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			secret.ResourceVersion = current.ResourceVersion
+		}
+		_, err := c.client.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		conflict = retry.IsConflict(err)
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, secret.Name)
+	}
+	return
+}
+
+func (c *secret) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates the secret or brings it in line with input, fields input leaves out are kept
+func (c *secret) ApplyContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	secret := new(corev1.Secret)
+	err = yaml.Unmarshal([]byte(input), secret)
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	manifest, err := apply.Manifest(input, corev1.SchemeGroupVersion.String(), "Secret")
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := secret.Namespace
-	_, err = c.client.CoreV1().Secrets(namespace).Update(secret)
+	secrets := c.client.CoreV1().Secrets(namespace)
+	_, err = apply.Do(ctx, c.applyOptions, c.retryPolicy, apply.Target{
+		Manifest: manifest,
+		Schema: &corev1.Secret{},
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			_, err := secrets.Patch(ctx, secret.Name, patchType, data, options)
+			return err
+		},
+		Get: func(ctx context.Context) ([]byte, error) {
+			current, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(current)
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			object := new(corev1.Secret)
+			if err := json.Unmarshal(manifest, object); err != nil {
+				return err
+			}
+			_, err := secrets.Create(ctx, object, metav1.CreateOptions{})
+			return err
+		},
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, secret.Name)
 	}
 	return
 }
 
 func (c *secret) Get(namespace string, name string) (ret string, err error) {
+	return c.GetContext(context.Background(), namespace, name)
+}
+
+func (c *secret) GetContext(ctx context.Context, namespace string, name string) (ret string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	secret, err := c.client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	var secret *corev1.Secret
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		secret, err = c.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
 	d, err := json.Marshal(secret)
 	if err != nil {
 		return "", err
 	}
 	ret = string(d)
 	return
-}
\ No newline at end of file
+}
+
+func (c *secret) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the secret with a YAML or JSON body and returns the patched object
+func (c *secret) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var secret *corev1.Secret
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		secret, err = c.client.CoreV1().Secrets(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(secret)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *secret) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of secrets, options.Limit and options.Continue page through the rest
+func (c *secret) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var secrets *corev1.SecretList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		secrets, err = c.client.CoreV1().Secrets(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&secrets.ListMeta)
+	for i := range secrets.Items {
+		d, err := json.Marshal(&secrets.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *secret) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of secrets lazily, one request per options.Limit items
+func (c *secret) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to secrets until ctx is done, reconnecting and resuming on its own
+func (c *secret) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CoreV1().Secrets(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.CoreV1().Secrets(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
+
+func (c *secret) ListSecrets(namespace string, labelSelector string) (secretList []SecretInfo, err error) {
+	return c.ListSecretsContext(context.Background(), namespace, labelSelector)
+}
+
+func (c *secret) ListSecretsContext(ctx context.Context, namespace string, labelSelector string) (secretList []SecretInfo, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var secrets *corev1.SecretList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		secrets, err = c.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labelSelector,
+		})
+		return
+	})
+	if err != nil {
+		err = c.wrapErr(err, namespace, "")
+		return
+	}
+	for _, e := range secrets.Items {
+		secretList = append(secretList, SecretInfo{
+			Namespace: e.Namespace,
+			Name: e.Name,
+			Labels: e.Labels,
+			Annotations: e.Annotations,
+			ResourceVersion: e.ResourceVersion,
+			Type: string(e.Type),
+			Data: e.Data,
+		})
+	}
+	return
+}
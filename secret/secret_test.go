@@ -0,0 +1,107 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const secretYAML = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: default
+  labels:
+    app: db
+type: Opaque
+stringData:
+  password: s3cret
+`
+
+func TestSecretCreateGetUpdateDelete(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	if err := c.Create(secretYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(secretYAML); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	ret, err := c.Get("default", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := new(corev1.Secret)
+	if err := json.Unmarshal([]byte(ret), secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("type = %s, want Opaque", secret.Type)
+	}
+	secret.Data = map[string][]byte{"password": []byte("changed")}
+	secret.StringData = nil
+	d, _ := json.Marshal(secret)
+	if err := c.Update(string(d)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("default", "db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("default", "db"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "db",
+				Namespace: "default",
+				Labels: map[string]string{"app": "db"},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"password": []byte("s3cret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cache",
+				Namespace: "default",
+				Labels: map[string]string{"app": "cache"},
+			},
+		},
+	))
+	secretList, err := c.ListSecrets("default", "app=db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secretList) != 1 {
+		t.Fatalf("ListSecrets(app=db) returned %d secrets, want 1", len(secretList))
+	}
+	if secretList[0].Name != "db" || string(secretList[0].Data["password"]) != "s3cret" || secretList[0].Type != "Opaque" {
+		t.Errorf("ListSecrets = %+v", secretList[0])
+	}
+}
+
+func TestSecretPatch(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "db",
+			Namespace: "default",
+		},
+	}))
+	ret, err := c.Patch("default", "db", types.JSONPatchType, `[{"op": "add", "path": "/metadata/labels", "value": {"rotated": "true"}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := new(corev1.Secret)
+	json.Unmarshal([]byte(ret), secret)
+	if secret.Labels["rotated"] != "true" {
+		t.Errorf("labels after Patch = %v, want rotated=true", secret.Labels)
+	}
+}
@@ -0,0 +1,309 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	k8sconfig "github.com/zhanghaohao/kubernetes-client/config"
+	"github.com/zhanghaohao/kubernetes-client/secret"
+)
+
+const (
+	defaultSecretKubeConfigKey = "kubeconfig"
+	defaultSecretClusterNameKey = "kubernetes-client/cluster-name"
+)
+
+// SecretSource describes member-cluster kubeconfigs stored as Secrets in a management cluster
+type SecretSource struct {
+	// Secrets reads the management cluster, e.g. management.GetClient("mgmt").Secret()
+	Secrets 				secret.Secret
+	// Namespace holding the Secrets, empty searches all namespaces
+	Namespace 				string
+	LabelSelector 			string
+	// KubeConfigKey is the data key holding the kubeconfig, default "kubeconfig"
+	KubeConfigKey 			string
+	// ClusterNameKey is the label or annotation naming the cluster, default "kubernetes-client/cluster-name".
+	// Secrets without it are registered under the Secret name.
+	ClusterNameKey 			string
+}
+
+// secretCluster is a member cluster read from a Secret
+type secretCluster struct {
+	clusterName 			string
+	source 					string
+	resourceVersion 		string
+	kubeConfig 				[]byte
+	labels 					map[string]string
+}
+
+type secretWatcher struct {
+	clients 				*k8sClients
+	source 					SecretSource
+	interval 				time.Duration
+	onReload 				ReloadFunc
+	// versions holds the resourceVersion each cluster was loaded from, keyed by cluster name
+	versions 				map[string]string
+	sources 				map[string]string
+	// invalid holds the last error reported for each unusable Secret, so it is reported once
+	invalid 				map[string]string
+	stopCh 					chan struct{}
+	stopOnce 				sync.Once
+}
+
+func (s SecretSource) kubeConfigKey() string {
+	if len(s.KubeConfigKey) == 0 {
+		return defaultSecretKubeConfigKey
+	}
+	return s.KubeConfigKey
+}
+
+func (s SecretSource) clusterNameKey() string {
+	if len(s.ClusterNameKey) == 0 {
+		return defaultSecretClusterNameKey
+	}
+	return s.ClusterNameKey
+}
+
+// list returns the member clusters sorted by name, plus an error for every Secret that could not be used
+func (s SecretSource) list(ctx context.Context) (clusters []secretCluster, invalid map[string]error, err error) {
+	if s.Secrets == nil {
+		return nil, nil, fmt.Errorf("secret source has no secret client")
+	}
+	secretList, err := s.Secrets.ListSecretsContext(ctx, s.Namespace, s.LabelSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	invalid = make(map[string]error)
+	seen := make(map[string]string)
+	for _, e := range secretList {
+		source := "secret:" + e.Namespace + "/" + e.Name
+		name := e.Labels[s.clusterNameKey()]
+		if len(name) == 0 {
+			name = e.Annotations[s.clusterNameKey()]
+		}
+		if len(name) == 0 {
+			name = e.Name
+		}
+		data, ok := e.Data[s.kubeConfigKey()]
+		if !ok || len(data) == 0 {
+			invalid[source] = fmt.Errorf("%s has no %s key", source, s.kubeConfigKey())
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			invalid[source] = fmt.Errorf("cluster name %s is used by both %s and %s", name, other, source)
+			continue
+		}
+		seen[name] = source
+		labels := make(map[string]string, len(e.Labels))
+		for k, v := range e.Labels {
+			if k != s.clusterNameKey() {
+				labels[k] = v
+			}
+		}
+		clusters = append(clusters, secretCluster{
+			clusterName: name,
+			source: source,
+			resourceVersion: e.ResourceVersion,
+			kubeConfig: data,
+			labels: labels,
+		})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].clusterName < clusters[j].clusterName
+	})
+	return
+}
+
+// loadSecretCluster registers the member cluster, the Secret's labels become the cluster labels
+func (k *k8sClients) loadSecretCluster(c secretCluster) (err error) {
+	kubeConfig, err := k8sconfig.ReadKubeConfigBytes(c.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.source, err)
+	}
+	metadata, _ := k.Metadata(c.clusterName)
+	metadata.Source = c.source
+	metadata.Labels = c.labels
+	metadata.LoadedAt = time.Now()
+	return k.loadConfig(c.clusterName, kubeConfig, metadata)
+}
+
+// InvalidSecretsError lists the Secrets NewFromSecrets skipped, keyed by secret:namespace/name
+type InvalidSecretsError struct {
+	Errors 					map[string]error
+}
+
+func (e *InvalidSecretsError) Error() string {
+	sources := make([]string, 0, len(e.Errors))
+	for source := range e.Errors {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	messages := make([]string, 0, len(sources))
+	for _, source := range sources {
+		messages = append(messages, e.Errors[source].Error())
+	}
+	return fmt.Sprintf("%d secret(s) skipped: %s", len(sources), strings.Join(messages, "; "))
+}
+
+// NewFromSecrets registers a cluster for every usable Secret. Secrets without a kubeconfig, with a
+// broken one or with a taken cluster name are skipped: clients then comes with an *InvalidSecretsError,
+// and is nil only when no Secret could be used.
+func NewFromSecrets(ctx context.Context, source SecretSource, opts ...Option) (clients K8SClients, err error) {
+	/*
+	build one k8s client per kubeconfig Secret found in the management cluster
+	 */
+	secretClusters, invalid, err := source.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(secretClusters) == 0 && len(invalid) == 0 {
+		err := fmt.Errorf("no kubeconfig secret matched %q in namespace %q", source.LabelSelector, source.Namespace)
+		return nil, err
+	}
+	c := newK8sClients(opts...)
+	for _, secretCluster := range secretClusters {
+		if err := c.loadSecretCluster(secretCluster); err != nil {
+			invalid[secretCluster.source] = err
+		}
+	}
+	if len(invalid) != 0 {
+		err = &InvalidSecretsError{
+			Errors: invalid,
+		}
+		if len(c.List()) == 0 {
+			return nil, err
+		}
+	}
+	clients = c
+	return
+}
+
+// WatchSecrets polls the management cluster: new Secrets register clusters, changed Secrets rebuild them
+// and deleted Secrets unregister them. Only clusters loaded from Secrets are ever removed.
+// The returned stop func (or Close) ends the watch.
+func (k *k8sClients) WatchSecrets(source SecretSource, interval time.Duration, onReload ReloadFunc) (stop func()) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	w := &secretWatcher{
+		clients: k,
+		source: source,
+		interval: interval,
+		onReload: onReload,
+		versions: make(map[string]string),
+		sources: make(map[string]string),
+		invalid: make(map[string]string),
+		stopCh: make(chan struct{}),
+	}
+	// clusters already registered, e.g. by NewFromSecrets, only reload once their Secret changes
+	ctx, cancel := context.WithTimeout(context.Background(), interval)
+	secretClusters, _, err := source.list(ctx)
+	cancel()
+	if err == nil {
+		for _, c := range secretClusters {
+			if metadata, ok := k.Metadata(c.clusterName); ok && metadata.Source == c.source {
+				w.versions[c.clusterName] = c.resourceVersion
+				w.sources[c.clusterName] = c.source
+			}
+		}
+	}
+	go w.run()
+	stop = func() {
+		w.stopOnce.Do(func() {
+			close(w.stopCh)
+		})
+	}
+	k.addStop(stop)
+	return
+}
+
+func (w *secretWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	w.check()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *secretWatcher) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+	defer cancel()
+	secretClusters, invalid, err := w.source.list(ctx)
+	if err != nil {
+		// the management cluster may be unreachable, keep every member cluster until it answers
+		return
+	}
+	present := make(map[string]bool, len(secretClusters))
+	for _, c := range secretClusters {
+		present[c.clusterName] = true
+	}
+	var removed []string
+	for name := range w.versions {
+		if !present[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		// the name may have been taken over by a cluster loaded some other way
+		if metadata, ok := w.clients.Metadata(name); ok && metadata.Source == w.sources[name] {
+			w.clients.Remove(name)
+		}
+		w.emit(ReloadEvent{
+			ClusterName: name,
+			Path: w.sources[name],
+			Op: ReloadRemoved,
+		})
+		delete(w.versions, name)
+		delete(w.sources, name)
+	}
+	for _, c := range secretClusters {
+		version, known := w.versions[c.clusterName]
+		if known && version == c.resourceVersion {
+			continue
+		}
+		event := ReloadEvent{
+			ClusterName: c.clusterName,
+			Path: c.source,
+			Op: ReloadAdded,
+		}
+		if known {
+			event.Op = ReloadUpdated
+		}
+		// report each change once, a broken Secret is retried when it changes again
+		w.versions[c.clusterName] = c.resourceVersion
+		w.sources[c.clusterName] = c.source
+		event.Err = w.clients.loadSecretCluster(c)
+		w.emit(event)
+	}
+	for source := range w.invalid {
+		if _, ok := invalid[source]; !ok {
+			delete(w.invalid, source)
+		}
+	}
+	for source, err := range invalid {
+		if w.invalid[source] == err.Error() {
+			continue
+		}
+		w.invalid[source] = err.Error()
+		w.emit(ReloadEvent{
+			Path: source,
+			Err: err,
+		})
+	}
+}
+
+func (w *secretWatcher) emit(event ReloadEvent) {
+	if w.onReload != nil {
+		w.onReload(event)
+	}
+}
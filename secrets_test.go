@@ -0,0 +1,170 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"github.com/zhanghaohao/kubernetes-client/secret"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newKubeConfigSecret(name string, clusterName string, server string, resourceVersion string) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "fleet-system",
+			ResourceVersion: resourceVersion,
+			Labels: map[string]string{
+				"env": "prod",
+			},
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte(fmt.Sprintf(testKubeConfig, name, server)),
+		},
+	}
+	if len(clusterName) != 0 {
+		s.Labels[defaultSecretClusterNameKey] = clusterName
+	}
+	return s
+}
+
+func secretSource(client *fake.Clientset) SecretSource {
+	return SecretSource{
+		Secrets: secret.NewForClient(client),
+		Namespace: "fleet-system",
+	}
+}
+
+func TestNewFromSecrets(t *testing.T) {
+	noKubeConfig := newKubeConfigSecret("no-kubeconfig", "", "", "1")
+	delete(noKubeConfig.Data, "kubeconfig")
+	broken := newKubeConfigSecret("broken", "", "", "1")
+	broken.Data["kubeconfig"] = []byte("clusters: [")
+	management := fake.NewSimpleClientset(
+		newKubeConfigSecret("prod", "prod-eu", "https://prod:6443", "1"),
+		newKubeConfigSecret("test", "", "https://test:6443", "1"),
+		newKubeConfigSecret("prod-copy", "prod-eu", "https://prod:6443", "1"),
+		noKubeConfig,
+		broken,
+	)
+	clients, err := NewFromSecrets(context.Background(), secretSource(management))
+	var invalid *InvalidSecretsError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("err = %v, want an *InvalidSecretsError", err)
+	}
+	if clients == nil {
+		t.Fatal("clients = nil, want the usable Secrets registered")
+	}
+	defer clients.Close()
+	if names := clients.List(); !reflect.DeepEqual(names, []string{"prod-eu", "test"}) {
+		t.Errorf("clusters = %v, want [prod-eu test]", names)
+	}
+	// prod and prod-copy both name prod-eu, whichever is listed second is skipped
+	metadata, _ := clients.Metadata("prod-eu")
+	winner, loser := "secret:fleet-system/prod", "secret:fleet-system/prod-copy"
+	if metadata.Source == loser {
+		winner, loser = loser, winner
+	}
+	if metadata.Source != winner || !reflect.DeepEqual(metadata.Labels, map[string]string{"env": "prod"}) {
+		t.Errorf("metadata = %+v, want a prod Secret as source and its labels without the cluster name", metadata)
+	}
+	want := []string{"secret:fleet-system/broken", "secret:fleet-system/no-kubeconfig", loser}
+	if len(invalid.Errors) != len(want) {
+		t.Errorf("skipped = %v, want %v", invalid.Errors, want)
+	}
+	for _, source := range want {
+		if invalid.Errors[source] == nil {
+			t.Errorf("%s was not reported", source)
+		}
+	}
+}
+
+func TestNewFromSecretsNothingUsable(t *testing.T) {
+	broken := newKubeConfigSecret("broken", "", "", "1")
+	broken.Data["kubeconfig"] = []byte("clusters: [")
+	clients, err := NewFromSecrets(context.Background(), secretSource(fake.NewSimpleClientset(broken)))
+	var invalid *InvalidSecretsError
+	if clients != nil || !errors.As(err, &invalid) {
+		t.Errorf("NewFromSecrets = %v, %v, want no clients and an *InvalidSecretsError", clients, err)
+	}
+	if _, err := NewFromSecrets(context.Background(), secretSource(fake.NewSimpleClientset())); err == nil {
+		t.Error("no Secrets: err = nil")
+	}
+}
+
+func TestWatchSecrets(t *testing.T) {
+	ctx := context.Background()
+	management := fake.NewSimpleClientset(newKubeConfigSecret("prod", "", "https://prod:6443", "1"))
+	source := secretSource(management)
+	clients, err := NewFromSecrets(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Close()
+	events := make(chan ReloadEvent, 10)
+	clients.WatchSecrets(source, 10*time.Millisecond, func(event ReloadEvent) {
+		events <- event
+	})
+	secrets := management.CoreV1().Secrets("fleet-system")
+
+	if _, err := secrets.Create(ctx, newKubeConfigSecret("test", "", "https://test:6443", "1"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	event := nextReloadEvent(t, events)
+	if event.Op != ReloadAdded || event.ClusterName != "test" || event.Err != nil || !clients.Has("test") {
+		t.Errorf("event after create = %+v, want test added", event)
+	}
+
+	if _, err := secrets.Update(ctx, newKubeConfigSecret("prod", "", "https://prod-2:6443", "2"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadUpdated || event.ClusterName != "prod" || event.Err != nil {
+		t.Errorf("event after update = %+v, want prod updated", event)
+	}
+
+	broken := newKubeConfigSecret("broken", "", "", "1")
+	delete(broken.Data, "kubeconfig")
+	if _, err := secrets.Create(ctx, broken, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Path != "secret:fleet-system/broken" || event.Err == nil {
+		t.Errorf("event after broken create = %+v, want an error for the Secret", event)
+	}
+
+	if err := secrets.Delete(ctx, "test", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadRemoved || event.ClusterName != "test" || clients.Has("test") {
+		t.Errorf("event after delete = %+v, want test removed", event)
+	}
+
+	if _, err := secrets.Create(ctx, newKubeConfigSecret("manual", "", "https://manual:6443", "1"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	nextReloadEvent(t, events)
+	// a cluster loaded some other way under the same name must survive the Secret going away
+	clients.LoadWithMetadata("manual", fake.NewSimpleClientset(), ClusterMetadata{Source: "portal"})
+	if err := secrets.Delete(ctx, "manual", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	event = nextReloadEvent(t, events)
+	if event.Op != ReloadRemoved || !clients.Has("manual") {
+		t.Errorf("event after delete = %+v, want manual kept as it was replaced by another source", event)
+	}
+
+	// nothing changes from here on, the broken Secret was reported once
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+}
@@ -1,15 +1,30 @@
 package service
 
 import (
+	"context"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"github.com/zhanghaohao/kubernetes-client/patch"
+	"github.com/zhanghaohao/kubernetes-client/apply"
+	"github.com/zhanghaohao/kubernetes-client/retry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
 	"k8s.io/api/core/v1"
 	"encoding/json"
 	"sigs.k8s.io/yaml"
 )
 
+const resourceType = "service"
+
 type service struct {
-	client 					*kubernetes.Clientset
+	client 					kubernetes.Interface
+	clusterName 			string
+	retryPolicy 			*retry.Policy
+	applyOptions 			*apply.Options
 	err 					error
 }
 
@@ -17,11 +32,24 @@ type Service interface {
 	SetErr(err error)
 	Create(input string) (err error)
 	Update(input string) (err error)
+	Apply(input string) (err error)
 	Delete(namespace string, serviceName string) (err error)
 	Get(namespace string, name string) (ret string, err error)
+	Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	List(namespace string, options list.Options) (page *list.Page, err error)
+	Iterate(namespace string, options list.Options) *list.Iterator
+	CreateContext(ctx context.Context, input string) (err error)
+	UpdateContext(ctx context.Context, input string) (err error)
+	ApplyContext(ctx context.Context, input string) (err error)
+	DeleteContext(ctx context.Context, namespace string, serviceName string) (err error)
+	GetContext(ctx context.Context, namespace string, name string) (ret string, err error)
+	PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error)
+	ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error)
+	IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator
+	Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error)
 }
 
-func NewForClient(client *kubernetes.Clientset) *service {
+func NewForClient(client kubernetes.Interface) *service {
 	return &service{
 		client: client,
 	}
@@ -31,12 +59,39 @@ func (c *service) SetErr(err error) {
 	c.err = err
 }
 
+func (c *service) SetClusterName(clusterName string) {
+	c.clusterName = clusterName
+}
+
+// SetRetryPolicy retries transient errors and update conflicts, nil sends every request once
+func (c *service) SetRetryPolicy(policy *retry.Policy) {
+	c.retryPolicy = policy
+}
+
+// SetApplyOptions sets the field manager and force flag of Apply, nil uses the defaults
+func (c *service) SetApplyOptions(options *apply.Options) {
+	c.applyOptions = options
+}
+
+func (c *service) wrapErr(err error, namespace string, name string) error {
+	return k8serrors.Wrap(err, c.clusterName, resourceType, namespace, name)
+}
+
 func (c *service) Get(namespace string, serviceName string) (serviceStr string, err error) {
+	return c.GetContext(context.Background(), namespace, serviceName)
+}
+
+func (c *service) GetContext(ctx context.Context, namespace string, serviceName string) (serviceStr string, err error) {
 	if c.err != nil {
 		return "", c.err
 	}
-	service, err := c.client.CoreV1().Services(namespace).Get(serviceName, metav1.GetOptions{})
+	var service *v1.Service
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		service, err = c.client.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+		return
+	})
 	if err != nil {
+		err = c.wrapErr(err, namespace, serviceName)
 		return
 	}
 	serviceJsonBytes, err := json.Marshal(service)
@@ -47,57 +102,216 @@ func (c *service) Get(namespace string, serviceName string) (serviceStr string,
 	return
 }
 
+func (c *service) Patch(namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	return c.PatchContext(context.Background(), namespace, name, patchType, input)
+}
+
+// PatchContext patches the service with a YAML or JSON body and returns the patched object
+func (c *service) PatchContext(ctx context.Context, namespace string, name string, patchType types.PatchType, input string) (ret string, err error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	data, err := patch.Data(patchType, input)
+	if err != nil {
+		return "", k8serrors.InvalidManifest(err, c.clusterName, resourceType)
+	}
+	var service *v1.Service
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() (err error) {
+		service, err = c.client.CoreV1().Services(namespace).Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+		return
+	})
+	if err != nil {
+		return "", c.wrapErr(err, namespace, name)
+	}
+	d, err := json.Marshal(service)
+	if err != nil {
+		return "", err
+	}
+	ret = string(d)
+	return
+}
+
+func (c *service) List(namespace string, options list.Options) (page *list.Page, err error) {
+	return c.ListContext(context.Background(), namespace, options)
+}
+
+// ListContext returns one page of services, options.Limit and options.Continue page through the rest
+func (c *service) ListContext(ctx context.Context, namespace string, options list.Options) (page *list.Page, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	var services *v1.ServiceList
+	err = retry.Do(ctx, c.retryPolicy, false, func() (err error) {
+		services, err = c.client.CoreV1().Services(namespace).List(ctx, options.ListOptions())
+		return
+	})
+	if err != nil {
+		return nil, c.wrapErr(err, namespace, "")
+	}
+	page = list.NewPage(&services.ListMeta)
+	for i := range services.Items {
+		d, err := json.Marshal(&services.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, string(d))
+	}
+	return
+}
+
+func (c *service) Iterate(namespace string, options list.Options) *list.Iterator {
+	return c.IterateContext(context.Background(), namespace, options)
+}
+
+// IterateContext walks every page of services lazily, one request per options.Limit items
+func (c *service) IterateContext(ctx context.Context, namespace string, options list.Options) *list.Iterator {
+	return list.NewIterator(ctx, options, func(ctx context.Context, options list.Options) (*list.Page, error) {
+		return c.ListContext(ctx, namespace, options)
+	})
+}
+
+// Watch streams changes to services until ctx is done, reconnecting and resuming on its own
+func (c *service) Watch(ctx context.Context, namespace string, options watch.Options) (events <-chan watch.Event, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	namespace = options.Namespace(namespace)
+	events = watch.Start(ctx, options, watch.Source{
+		ClusterName: c.clusterName,
+		ResourceType: resourceType,
+		Namespace: namespace,
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CoreV1().Services(namespace).List(ctx, options)
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			return c.client.CoreV1().Services(namespace).Watch(ctx, options)
+		},
+		Policy: c.retryPolicy,
+	})
+	return
+}
+
 func (c *service) Create(input string) (err error) {
+	return c.CreateContext(context.Background(), input)
+}
+
+func (c *service) CreateContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	service := new(v1.Service)
 	err = yaml.Unmarshal([]byte(input), service)
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
-	// output for debug
-	_, err = yaml.Marshal(service)
+	namespace := service.Namespace
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		_, err := c.client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, service.Name)
+	}
+	return
+}
+
+func (c *service) Update(input string) (err error) {
+	return c.UpdateContext(context.Background(), input)
+}
+
+func (c *service) UpdateContext(ctx context.Context, input string) (err error) {
+	if c.err != nil {
+		return c.err
+	}
+	service := new(v1.Service)
+	err = yaml.Unmarshal([]byte(input), service)
+	if err != nil {
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := service.Namespace
-	_, err = c.client.CoreV1().Services(namespace).Create(service)
+	conflict := false
+	err = retry.Do(ctx, c.retryPolicy, true, func() error {
+		if conflict {
+			// reapply our object on top of the live resourceVersion
+			current, err := c.client.CoreV1().Services(namespace).Get(ctx, service.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			service.ResourceVersion = current.ResourceVersion
+		}
+		_, err := c.client.CoreV1().Services(namespace).Update(ctx, service, metav1.UpdateOptions{})
+		conflict = retry.IsConflict(err)
+		return err
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, service.Name)
 	}
 	return
 }
 
-func (c *service) Update(input string) (err error) {
+func (c *service) Apply(input string) (err error) {
+	return c.ApplyContext(context.Background(), input)
+}
+
+// ApplyContext creates the service or brings it in line with input, fields input leaves out are kept
+func (c *service) ApplyContext(ctx context.Context, input string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
 	service := new(v1.Service)
 	err = yaml.Unmarshal([]byte(input), service)
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
-	// output for debug
-	_, err = yaml.Marshal(service)
+	manifest, err := apply.Manifest(input, v1.SchemeGroupVersion.String(), "Service")
 	if err != nil {
-		return
+		return k8serrors.InvalidManifest(err, c.clusterName, resourceType)
 	}
 	namespace := service.Namespace
-	_, err = c.client.CoreV1().Services(namespace).Update(service)
+	services := c.client.CoreV1().Services(namespace)
+	_, err = apply.Do(ctx, c.applyOptions, c.retryPolicy, apply.Target{
+		Manifest: manifest,
+		Schema: &v1.Service{},
+		Patch: func(ctx context.Context, patchType types.PatchType, data []byte, options metav1.PatchOptions) error {
+			_, err := services.Patch(ctx, service.Name, patchType, data, options)
+			return err
+		},
+		Get: func(ctx context.Context) ([]byte, error) {
+			current, err := services.Get(ctx, service.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(current)
+		},
+		Create: func(ctx context.Context, manifest []byte) error {
+			object := new(v1.Service)
+			if err := json.Unmarshal(manifest, object); err != nil {
+				return err
+			}
+			_, err := services.Create(ctx, object, metav1.CreateOptions{})
+			return err
+		},
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, service.Name)
 	}
 	return
 }
 
 func (c *service) Delete(namespace string, serviceName string) (err error) {
+	return c.DeleteContext(context.Background(), namespace, serviceName)
+}
+
+func (c *service) DeleteContext(ctx context.Context, namespace string, serviceName string) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	err = c.client.CoreV1().Services(namespace).Delete(serviceName, &metav1.DeleteOptions{})
+	err = retry.DoNonIdempotent(ctx, c.retryPolicy, func() error {
+		return c.client.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+	})
 	if err != nil {
-		return
+		return c.wrapErr(err, namespace, serviceName)
 	}
 	return
-}
\ No newline at end of file
+}
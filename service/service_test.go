@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/list"
+	"github.com/zhanghaohao/kubernetes-client/watch"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const serviceYAML = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  selector:
+    app: nginx
+  ports:
+  - port: 80
+`
+
+func newService(name string, labels map[string]string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "default",
+			Labels: labels,
+		},
+	}
+}
+
+func TestServiceCreateGetUpdateDelete(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	if err := c.Create(serviceYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(serviceYAML); !errors.Is(err, k8serrors.ErrAlreadyExists) {
+		t.Errorf("second Create: err = %v, want ErrAlreadyExists", err)
+	}
+	ret, err := c.Get("default", "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := new(v1.Service)
+	if err := json.Unmarshal([]byte(ret), service); err != nil {
+		t.Fatal(err)
+	}
+	service.Spec.Ports[0].Port = 8080
+	d, _ := json.Marshal(service)
+	if err := c.Update(string(d)); err != nil {
+		t.Fatal(err)
+	}
+	ret, _ = c.Get("default", "nginx")
+	json.Unmarshal([]byte(ret), service)
+	if service.Spec.Ports[0].Port != 8080 {
+		t.Errorf("port after Update = %d, want 8080", service.Spec.Ports[0].Port)
+	}
+	if err := c.Delete("default", "nginx"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("default", "nginx"); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServiceUpdateMissing(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset())
+	if err := c.Update(serviceYAML); !errors.Is(err, k8serrors.ErrNotFound) {
+		t.Errorf("Update missing: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServicePatchAndList(t *testing.T) {
+	c := NewForClient(fake.NewSimpleClientset(
+		newService("nginx", map[string]string{"app": "nginx"}),
+		newService("redis", map[string]string{"app": "redis"}),
+	))
+	ret, err := c.Patch("default", "nginx", types.MergePatchType, `{"metadata":{"annotations":{"owner":"web"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := new(v1.Service)
+	json.Unmarshal([]byte(ret), service)
+	if service.Annotations["owner"] != "web" {
+		t.Errorf("annotations after Patch = %v, want owner=web", service.Annotations)
+	}
+	page, err := c.List("", list.Options{AllNamespaces: true, LabelSelector: "app in (nginx, redis)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("List returned %d items, want 2", len(page.Items))
+	}
+}
+
+func TestServiceWatch(t *testing.T) {
+	client := fake.NewSimpleClientset(newService("nginx", nil))
+	c := NewForClient(client)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := c.Watch(ctx, "default", watch.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != watch.Added || event.Name != "nginx" {
+			t.Errorf("first event = %+v, want Added nginx", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event before the timeout")
+	}
+	cancel()
+	// the channel is closed once ctx is done
+	for range events {
+	}
+}
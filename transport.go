@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"net/http"
+	"strings"
+)
+
+type requestInfo struct {
+	verb 					string
+	resource 				string
+	subresource 			string
+	namespace 				string
+	name 					string
+}
+
+// parseRequestInfo recovers the kubernetes verb and object from an API request URL, e.g.
+// GET /apis/apps/v1/namespaces/default/deployments/nginx is a get of deployments default/nginx
+func parseRequestInfo(req *http.Request) (info requestInfo) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		// non resource url such as /version or /healthz
+		info.verb = strings.ToLower(req.Method)
+		info.name = req.URL.Path
+		return
+	}
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		if len(parts) == 2 || parts[2] == "status" || parts[2] == "finalize" {
+			// the namespace object itself
+			info.resource = "namespaces"
+			info.name = parts[1]
+			info.subresource = strings.Join(parts[2:], "/")
+		} else {
+			info.namespace = parts[1]
+			parts = parts[2:]
+		}
+	}
+	if len(info.resource) == 0 && len(parts) > 0 {
+		info.resource = parts[0]
+		if len(parts) > 1 {
+			info.name = parts[1]
+		}
+		if len(parts) > 2 {
+			info.subresource = strings.Join(parts[2:], "/")
+		}
+	}
+	query := req.URL.Query()
+	switch req.Method {
+	case http.MethodGet:
+		switch {
+		case query.Get("watch") == "true" || query.Get("watch") == "1":
+			info.verb = "watch"
+		case len(info.name) == 0:
+			info.verb = "list"
+		default:
+			info.verb = "get"
+		}
+	case http.MethodPost:
+		info.verb = "create"
+	case http.MethodPut:
+		info.verb = "update"
+	case http.MethodPatch:
+		info.verb = "patch"
+	case http.MethodDelete:
+		if len(info.name) == 0 {
+			info.verb = "deletecollection"
+		} else {
+			info.verb = "delete"
+		}
+	default:
+		info.verb = strings.ToLower(req.Method)
+	}
+	return
+}
+
+// isStreaming tells requests whose response body must not be buffered
+func isStreaming(req *http.Request, info requestInfo) bool {
+	return info.verb == "watch" || req.URL.Query().Get("follow") == "true"
+}
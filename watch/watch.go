@@ -0,0 +1,312 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	"github.com/zhanghaohao/kubernetes-client/retry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type EventType string
+
+const (
+	Added EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted EventType = "DELETED"
+	// Error reports a failure, Err is set and there is no object. Transient failures are retried,
+	// any other, e.g. a 403 or 404, is the last event before the channel closes.
+	Error EventType = "ERROR"
+)
+
+const (
+	eventBuffer = 100
+	relistPageSize = 500
+	// minBackoff spaces reconnects when the server keeps closing the watch right away
+	// or the policy leaves InitialBackoff unset
+	minBackoff = time.Second
+)
+
+type Options struct {
+	LabelSelector 			string
+	FieldSelector 			string
+	// AllNamespaces watches every namespace, the namespace argument is then ignored
+	AllNamespaces 			bool
+	// ResourceVersion resumes after a version seen before, e.g. the last Event.ResourceVersion.
+	// Empty lists first and sends every existing object as Added.
+	ResourceVersion 		string
+}
+
+type Event struct {
+	Type 					EventType
+	ClusterName 			string
+	Namespace 				string
+	Name 					string
+	ResourceVersion 		string
+	// Object is the object as JSON like Get returns it, for Deleted its last known state
+	Object 					string
+	Err 					error
+}
+
+// ListFunc and WatchFunc call the resource's client, namespace and selectors are already applied
+type ListFunc func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error)
+type WatchFunc func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error)
+
+// Source is what a resource package hands to Start
+type Source struct {
+	ClusterName 			string
+	ResourceType 			string
+	Namespace 				string
+	List 					ListFunc
+	Watch 					WatchFunc
+	// Policy spaces reconnects after errors, at least a second apart. MaxAttempts is ignored as the watch retries until ctx is done
+	Policy 					*retry.Policy
+}
+
+type watcher struct {
+	source 					Source
+	options 				Options
+	policy 					retry.Policy
+	events 					chan Event
+	resourceVersion 		string
+	// known holds the last state of every object seen, keyed by namespace/name,
+	// so a relist can tell what changed or disappeared while the watch was down
+	known 					map[string]Event
+}
+
+func (o Options) Namespace(namespace string) string {
+	if o.AllNamespaces {
+		return metav1.NamespaceAll
+	}
+	return namespace
+}
+
+func (o Options) ListOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: o.LabelSelector,
+		FieldSelector: o.FieldSelector,
+	}
+}
+
+// Start watches the source until ctx is done or an error retry.IsTransient rejects, then closes
+// the channel. Timeouts and dropped connections reconnect from the last resourceVersion, a 410 Gone
+// lists again and sends the differences as Added, Modified and Deleted events.
+func Start(ctx context.Context, options Options, source Source) <-chan Event {
+	w := &watcher{
+		source: source,
+		options: options,
+		policy: retry.DefaultPolicy,
+		events: make(chan Event, eventBuffer),
+		resourceVersion: options.ResourceVersion,
+		known: make(map[string]Event),
+	}
+	if source.Policy != nil {
+		w.policy = *source.Policy
+	}
+	go w.run(ctx)
+	return w.events
+}
+
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.events)
+	failures := 0
+	for {
+		relist := len(w.resourceVersion) == 0
+		var err error
+		if relist {
+			err = w.relist(ctx)
+		}
+		if err == nil {
+			err = w.watch(ctx)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err == nil:
+			// the server closed the watch, carry on from the last resourceVersion
+			failures = 0
+			if !w.wait(ctx, minBackoff) {
+				return
+			}
+			continue
+		case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+			w.resourceVersion = ""
+			if !relist {
+				// the resourceVersion is too old, list again right away
+				continue
+			}
+		default:
+			w.send(ctx, Event{
+				Type: Error,
+				ClusterName: w.source.ClusterName,
+				Err: k8serrors.Wrap(err, w.source.ClusterName, w.source.ResourceType, w.source.Namespace, ""),
+			})
+			// reconnecting does not help when forbidden or the resource does not exist
+			if !retry.IsTransient(err) {
+				return
+			}
+		}
+		failures++
+		backoff := w.policy.Backoff(failures)
+		if backoff < minBackoff {
+			backoff = minBackoff
+		}
+		if !w.wait(ctx, backoff) {
+			return
+		}
+	}
+}
+
+// wait sleeps for d, false when ctx is done first
+func (w *watcher) wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// relist lists every object page by page and sends what changed since the objects were last seen
+func (w *watcher) relist(ctx context.Context) (err error) {
+	options := w.options.ListOptions()
+	options.Limit = relistPageSize
+	present := make(map[string]bool)
+	var resourceVersion string
+	var pending []Event
+	for {
+		object, err := w.source.List(ctx, options)
+		if err != nil {
+			return err
+		}
+		listMeta, err := meta.ListAccessor(object)
+		if err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(object)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			event, err := w.newEvent(Added, item)
+			if err != nil {
+				return err
+			}
+			key := event.Namespace + "/" + event.Name
+			present[key] = true
+			if last, ok := w.known[key]; ok {
+				if last.ResourceVersion == event.ResourceVersion {
+					continue
+				}
+				event.Type = Modified
+			}
+			pending = append(pending, event)
+		}
+		resourceVersion = listMeta.GetResourceVersion()
+		options.Continue = listMeta.GetContinue()
+		if len(options.Continue) == 0 {
+			break
+		}
+	}
+	// send only once the whole list is in, a relist failing halfway starts over without duplicates
+	for _, event := range pending {
+		w.known[event.Namespace+"/"+event.Name] = event
+		if !w.send(ctx, event) {
+			return ctx.Err()
+		}
+	}
+	for key, last := range w.known {
+		if present[key] {
+			continue
+		}
+		delete(w.known, key)
+		last.Type = Deleted
+		if !w.send(ctx, last) {
+			return ctx.Err()
+		}
+	}
+	w.resourceVersion = resourceVersion
+	return
+}
+
+// watch streams events until the server closes the watch or reports an error
+func (w *watcher) watch(ctx context.Context) (err error) {
+	options := w.options.ListOptions()
+	options.ResourceVersion = w.resourceVersion
+	options.AllowWatchBookmarks = true
+	stream, err := w.source.Watch(ctx, options)
+	if err != nil {
+		return
+	}
+	defer stream.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-stream.ResultChan():
+			if !ok {
+				return nil
+			}
+			switch e.Type {
+			case apiwatch.Error:
+				return apierrors.FromObject(e.Object)
+			case apiwatch.Bookmark:
+				if accessor, err := meta.Accessor(e.Object); err == nil {
+					w.resourceVersion = accessor.GetResourceVersion()
+				}
+			case apiwatch.Added, apiwatch.Modified, apiwatch.Deleted:
+				event, err := w.newEvent(EventType(e.Type), e.Object)
+				if err != nil {
+					return err
+				}
+				key := event.Namespace + "/" + event.Name
+				if event.Type == Deleted {
+					delete(w.known, key)
+				} else {
+					w.known[key] = event
+				}
+				w.resourceVersion = event.ResourceVersion
+				if !w.send(ctx, event) {
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+func (w *watcher) newEvent(eventType EventType, object runtime.Object) (event Event, err error) {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return
+	}
+	d, err := json.Marshal(object)
+	if err != nil {
+		return
+	}
+	event = Event{
+		Type: eventType,
+		ClusterName: w.source.ClusterName,
+		Namespace: accessor.GetNamespace(),
+		Name: accessor.GetName(),
+		ResourceVersion: accessor.GetResourceVersion(),
+		Object: string(d),
+	}
+	return
+}
+
+func (w *watcher) send(ctx context.Context, event Event) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.events <- event:
+		return true
+	}
+}
@@ -0,0 +1,256 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	k8serrors "github.com/zhanghaohao/kubernetes-client/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apiwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+// fakeSource hands out the lists and watches queued by the test and records every call
+type fakeSource struct {
+	mu 						sync.Mutex
+	lists 					[]*corev1.PodList
+	watchErrs 				[]error
+	watches 				chan *apiwatch.FakeWatcher
+	listCalls 				[]metav1.ListOptions
+	watchCalls 				[]metav1.ListOptions
+	watchTimes 				[]time.Time
+}
+
+func newFakeSource(lists ...*corev1.PodList) *fakeSource {
+	return &fakeSource{
+		lists: lists,
+		watches: make(chan *apiwatch.FakeWatcher, 10),
+	}
+}
+
+func (f *fakeSource) source() Source {
+	return Source{
+		ClusterName: "cluster1",
+		ResourceType: "pod",
+		Namespace: "default",
+		List: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.listCalls = append(f.listCalls, options)
+			if len(f.lists) == 0 {
+				return &corev1.PodList{}, nil
+			}
+			list := f.lists[0]
+			f.lists = f.lists[1:]
+			return list, nil
+		},
+		Watch: func(ctx context.Context, options metav1.ListOptions) (apiwatch.Interface, error) {
+			f.mu.Lock()
+			f.watchCalls = append(f.watchCalls, options)
+			f.watchTimes = append(f.watchTimes, time.Now())
+			if len(f.watchErrs) != 0 {
+				err := f.watchErrs[0]
+				f.watchErrs = f.watchErrs[1:]
+				f.mu.Unlock()
+				return nil, err
+			}
+			f.mu.Unlock()
+			w := apiwatch.NewFakeWithChanSize(10, false)
+			f.watches <- w
+			return w, nil
+		},
+	}
+}
+
+func (f *fakeSource) calls() (lists []metav1.ListOptions, watches []metav1.ListOptions, times []time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append(lists, f.listCalls...), append(watches, f.watchCalls...), append(times, f.watchTimes...)
+}
+
+func (f *fakeSource) nextWatch(t *testing.T) *apiwatch.FakeWatcher {
+	t.Helper()
+	select {
+	case w := <-f.watches:
+		return w
+	case <-time.After(5 * time.Second):
+		t.Fatal("no watch started before the timeout")
+		return nil
+	}
+}
+
+func newPod(name string, resourceVersion string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Namespace: "default",
+			ResourceVersion: resourceVersion,
+		},
+	}
+}
+
+func newPodList(resourceVersion string, pods ...*corev1.Pod) *corev1.PodList {
+	list := &corev1.PodList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: resourceVersion,
+		},
+	}
+	for _, pod := range pods {
+		list.Items = append(list.Items, *pod)
+	}
+	return list
+}
+
+func next(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("channel closed, want another event")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event before the timeout")
+	}
+	return Event{}
+}
+
+func expect(t *testing.T, events <-chan Event, eventType EventType, name string, resourceVersion string) {
+	t.Helper()
+	event := next(t, events)
+	if event.Type != eventType || event.Name != name || event.ResourceVersion != resourceVersion {
+		t.Errorf("event = %s %s@%s, want %s %s@%s", event.Type, event.Name, event.ResourceVersion, eventType, name, resourceVersion)
+	}
+}
+
+func expectClosed(t *testing.T, events <-chan Event) {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if ok {
+			t.Fatalf("event = %+v, want the channel closed", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel still open after the timeout")
+	}
+}
+
+// a known resourceVersion skips the list, a closed watch reconnects from the last event seen
+func TestWatchResumesFromResourceVersion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeSource()
+	events := Start(ctx, Options{ResourceVersion: "5"}, f.source())
+	w := f.nextWatch(t)
+	w.Add(newPod("nginx", "6"))
+	w.Modify(newPod("nginx", "7"))
+	expect(t, events, Added, "nginx", "6")
+	expect(t, events, Modified, "nginx", "7")
+	w.Stop()
+	w = f.nextWatch(t)
+	w.Delete(newPod("nginx", "8"))
+	expect(t, events, Deleted, "nginx", "8")
+	lists, watches, _ := f.calls()
+	if len(lists) != 0 {
+		t.Errorf("%d list calls, want none", len(lists))
+	}
+	if len(watches) != 2 || watches[0].ResourceVersion != "5" || watches[1].ResourceVersion != "7" {
+		t.Errorf("watch calls = %+v, want resourceVersion 5 then 7", watches)
+	}
+	cancel()
+	expectClosed(t, events)
+}
+
+// after a 410 the watch lists again and only sends what changed while it was down
+func TestWatchRelistsOnGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeSource(
+		newPodList("10", newPod("nginx", "3"), newPod("redis", "4"), newPod("mysql", "5")),
+		newPodList("20", newPod("nginx", "3"), newPod("redis", "15"), newPod("etcd", "18")),
+	)
+	events := Start(ctx, Options{}, f.source())
+	expect(t, events, Added, "nginx", "3")
+	expect(t, events, Added, "redis", "4")
+	expect(t, events, Added, "mysql", "5")
+	w := f.nextWatch(t)
+	w.Error(&metav1.Status{
+		Status: metav1.StatusFailure,
+		Code: http.StatusGone,
+		Reason: metav1.StatusReasonExpired,
+		Message: "too old resource version",
+	})
+	expect(t, events, Modified, "redis", "15")
+	expect(t, events, Added, "etcd", "18")
+	expect(t, events, Deleted, "mysql", "5")
+	f.nextWatch(t)
+	lists, watches, _ := f.calls()
+	if len(lists) != 2 {
+		t.Errorf("%d list calls, want 2", len(lists))
+	}
+	if len(watches) != 2 || watches[0].ResourceVersion != "10" || watches[1].ResourceVersion != "20" {
+		t.Errorf("watch calls = %+v, want resourceVersion 10 then 20", watches)
+	}
+}
+
+// failed reconnects send an Error event each and wait at least minBackoff before trying again
+func TestWatchReconnectBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeSource()
+	f.watchErrs = []error{
+		apierrors.NewInternalError(errors.New("etcd leader changed")),
+		apierrors.NewServiceUnavailable("apiserver restarting"),
+	}
+	events := Start(ctx, Options{ResourceVersion: "5"}, f.source())
+	for i := 0; i < 2; i++ {
+		event := next(t, events)
+		if event.Type != Error || event.Err == nil {
+			t.Fatalf("event %d = %+v, want an Error event", i, event)
+		}
+	}
+	w := f.nextWatch(t)
+	w.Add(newPod("nginx", "6"))
+	expect(t, events, Added, "nginx", "6")
+	_, watches, times := f.calls()
+	if len(watches) != 3 {
+		t.Fatalf("%d watch calls, want 3", len(watches))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < minBackoff {
+			t.Errorf("reconnect %d after %s, want at least %s", i, gap, minBackoff)
+		}
+	}
+	if watches[2].ResourceVersion != "5" {
+		t.Errorf("resourceVersion after the failures = %s, want 5", watches[2].ResourceVersion)
+	}
+}
+
+// errors reconnecting cannot fix end the watch after one Error event
+func TestWatchStopsOnPermanentErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeSource()
+	f.watchErrs = []error{
+		apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("rbac")),
+	}
+	events := Start(ctx, Options{ResourceVersion: "5"}, f.source())
+	event := next(t, events)
+	if event.Type != Error {
+		t.Fatalf("event = %+v, want an Error event", event)
+	}
+	var e *k8serrors.Error
+	if !errors.As(event.Err, &e) || e.Cluster != "cluster1" || !apierrors.IsForbidden(errors.Unwrap(event.Err)) {
+		t.Errorf("Err = %v, want the forbidden error wrapped with the cluster", event.Err)
+	}
+	expectClosed(t, events)
+	if _, watches, _ := f.calls(); len(watches) != 1 {
+		t.Errorf("%d watch calls, want 1", len(watches))
+	}
+}